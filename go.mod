@@ -1,3 +1,23 @@
 module github.com/kakabei/kfgolib
 
 go 1.21.13
+
+require (
+	github.com/go-logr/logr v1.4.1
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+	go.uber.org/zap v1.27.0
+	google.golang.org/grpc v1.62.1
+	google.golang.org/protobuf v1.32.0
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
+	gorm.io/gorm v1.25.7
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/net v0.20.0 // indirect
+	golang.org/x/sys v0.16.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240123012728-ef4313101c80 // indirect
+)
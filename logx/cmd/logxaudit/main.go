@@ -0,0 +1,56 @@
+// Command logxaudit verifies the hash chain of logx audit files.
+//
+//	logxaudit [-key key] file...
+//
+// The HMAC key defaults to the LOGX_AUDIT_KEY environment variable. It exits
+// with status 1 if an entry is modified, deleted, inserted or reordered.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+func main() {
+	key := flag.String("key", "", "HMAC key of the audit log, default is $"+tracing.EnvAuditKey)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: logxaudit [-key key] file...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *key == "" {
+		*key = os.Getenv(tracing.EnvAuditKey)
+	}
+	if *key == "" {
+		fmt.Fprintln(os.Stderr, "logxaudit: no key, use -key or $"+tracing.EnvAuditKey)
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, filename := range flag.Args() {
+		n, err := tracing.VerifyAuditFile(filename, []byte(*key))
+		var auditErr *tracing.AuditError
+		switch {
+		case errors.As(err, &auditErr):
+			fmt.Printf("%s: FAILED after %d entries: %v\n", filename, n, err)
+			failed = true
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "logxaudit: %v\n", err)
+			failed = true
+		default:
+			fmt.Printf("%s: OK, %d entries\n", filename, n)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
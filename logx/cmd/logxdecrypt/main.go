@@ -0,0 +1,80 @@
+// Command logxdecrypt writes the plaintext of encrypted logx files to the
+// standard output, rotated files compressed with gzip are supported.
+//
+//	logxdecrypt [-key key] file...
+//
+// The key is in hex or base64 and defaults to the LOGX_ENCRYPTION_KEY
+// environment variable. With no file, the standard input is decrypted.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+func main() {
+	keyFlag := flag.String("key", "", "AES key in hex or base64, default is $"+tracing.EnvEncryptionKey)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: logxdecrypt [-key key] [file...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	s := *keyFlag
+	if s == "" {
+		s = os.Getenv(tracing.EnvEncryptionKey)
+	}
+	if s == "" {
+		fmt.Fprintln(os.Stderr, "logxdecrypt: no key, use -key or $"+tracing.EnvEncryptionKey)
+		os.Exit(2)
+	}
+	key, err := tracing.ParseEncryptionKey(s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logxdecrypt: %v\n", err)
+		os.Exit(2)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	if flag.NArg() == 0 {
+		if err := decrypt(out, os.Stdin, key); err != nil {
+			out.Flush()
+			fmt.Fprintf(os.Stderr, "logxdecrypt: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	failed := false
+	for _, filename := range flag.Args() {
+		f, err := os.Open(filename)
+		if err == nil {
+			err = decrypt(out, f, key)
+			f.Close()
+		}
+		if err != nil {
+			out.Flush()
+			fmt.Fprintf(os.Stderr, "logxdecrypt: %s: %v\n", filename, err)
+			failed = true
+		}
+	}
+	if failed {
+		out.Flush()
+		os.Exit(1)
+	}
+}
+
+func decrypt(w io.Writer, r io.Reader, key []byte) error {
+	dr, err := tracing.NewDecryptReader(r, key)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, dr)
+	return err
+}
@@ -0,0 +1,161 @@
+// Command logxq queries the JSON log files of logx, including the rotated
+// backups compressed with gzip, in order.
+//
+//	logxq [flags] file...
+//
+// A file is a log file, which is read after its backups, or a directory of
+// log files. The entries are filtered by the flags, e.g.
+//
+//	logxq -trace 4bf92f3577b34da6 -level warn -since 1h -where 'httpRequest.status>=500' app.log
+//	logxq -f -app order -o json app.log
+//	logxq -schema time=ts,level=level,message=msg app.log
+//
+// With -f, the file is followed like tail -f across rotations.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/logreader"
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+func main() {
+	var (
+		filter  logreader.Filter
+		schema  = logreader.DefaultSchema
+		since   = flag.String("since", "", "entries at or after the time, in RFC 3339, 2006-01-02 15:04:05 or a duration before now")
+		until   = flag.String("until", "", "entries before the time, see -since")
+		follow  = flag.Bool("f", false, "follow the file like tail -f")
+		output  = flag.String("o", "console", "output format, console or json")
+		limit   = flag.Int("n", 0, "maximum number of entries, 0 is no limit")
+		decrypt = flag.Bool("decrypt", false, "decrypt the files with -key")
+		keyFlag = flag.String("key", "", "AES key in hex or base64 of -decrypt, default is $"+tracing.EnvEncryptionKey)
+	)
+	flag.StringVar(&filter.TraceID, "trace", "", "TRACE_ID of the entries")
+	flag.StringVar(&filter.App, "app", "", "LAPP of the entries")
+	flag.StringVar(&filter.Level, "level", "", "minimum level of the entries")
+	flag.Var(&schema, "schema", "keys of the entries as name=key pairs, the names are time, level, message and caller")
+	flag.Var(&filter.Exprs, "where", "field expression, e.g. key=value, key!=value, key~regexp, key>number or key, may be repeated")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: logxq [flags] file...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	now := time.Now()
+	var err error
+	if *since != "" {
+		if filter.Since, err = logreader.ParseTimeArg(*since, now); err != nil {
+			usage(err)
+		}
+	}
+	if *until != "" {
+		if filter.Until, err = logreader.ParseTimeArg(*until, now); err != nil {
+			usage(err)
+		}
+	}
+	if filter.Level != "" && !logreader.ValidLevel(filter.Level) {
+		usage(fmt.Errorf("invalid level %q", filter.Level))
+	}
+	if *output != "console" && *output != "json" {
+		usage(fmt.Errorf("invalid output format %q", *output))
+	}
+	if flag.NArg() == 0 || (*follow && flag.NArg() != 1) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := logreader.Options{Schema: schema}
+	if *decrypt {
+		s := *keyFlag
+		if s == "" {
+			s = os.Getenv(tracing.EnvEncryptionKey)
+		}
+		if opts.Key, err = tracing.ParseEncryptionKey(s); err != nil {
+			usage(err)
+		}
+	}
+
+	var r *logreader.Reader
+	if *follow {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		r = logreader.Follow(ctx, flag.Arg(0), opts)
+	} else {
+		var files []string
+		for _, name := range flag.Args() {
+			fs, err := logreader.Files(name)
+			if err != nil {
+				fail(err)
+			}
+			files = append(files, fs...)
+		}
+		r = logreader.NewReader(files, opts)
+	}
+	defer r.Close()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for n := 0; *limit == 0 || n < *limit; {
+		e, err := r.Next()
+		if err == io.EOF || errors.Is(err, context.Canceled) {
+			break
+		}
+		if err != nil {
+			out.Flush()
+			fail(err)
+		}
+		if !filter.Match(e) {
+			continue
+		}
+		n++
+		if *output == "json" {
+			out.Write(e.Raw)
+			out.WriteByte('\n')
+		} else {
+			writeConsole(out, e, schema)
+		}
+		if *follow {
+			out.Flush()
+		}
+	}
+}
+
+// writeConsole writes an entry as the console encoding of logx, the keys of
+// the schema are written before the other fields.
+func writeConsole(w *bufio.Writer, e *logreader.Entry, schema logreader.Schema) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s", e.FieldString(schema.TimeKey), e.Level, e.Caller, e.Message)
+
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		if k != schema.TimeKey && k != schema.LevelKey && k != schema.MessageKey && k != schema.CallerKey {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "\t%s=%s", k, e.FieldString(k))
+	}
+	w.WriteByte('\n')
+}
+
+func usage(err error) {
+	fmt.Fprintf(os.Stderr, "logxq: %v\n", err)
+	os.Exit(2)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "logxq: %v\n", err)
+	os.Exit(1)
+}
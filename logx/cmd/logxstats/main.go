@@ -0,0 +1,343 @@
+// Command logxstats summarizes the JSON log files of logx.
+//
+//	logxstats [flags] file...
+//
+// A file is a log file, which is read after its rotated backups, or a
+// directory of log files. The report has the top messages by count, with
+// the numbers and IDs replaced by placeholders, the error rate over time
+// buckets, the slowest requests by latency, and the distributions of the
+// httpRequest status and retCode.
+//
+// With -compare, the entries before the time are the baseline of the ones
+// after it, and the error templates which are new after it are reported,
+// e.g. after a deploy:
+//
+//	logxstats -since 2h -compare '2024-05-01 10:30' app.log
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/logreader"
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+// barWidth is the width of the bar of an error rate of 100%.
+const barWidth = 40
+
+func main() {
+	var (
+		filter  logreader.Filter
+		schema  = logreader.DefaultSchema
+		since   = flag.String("since", "", "entries at or after the time, in RFC 3339, 2006-01-02 15:04:05 or a duration before now")
+		until   = flag.String("until", "", "entries before the time, see -since")
+		compare = flag.String("compare", "", "time which splits the entries into the baseline and the current window, see -since")
+		bucket  = flag.Duration("bucket", time.Minute, "duration of the buckets of the error rate")
+		top     = flag.Int("top", 20, "number of the top messages")
+		slow    = flag.Int("slow", 10, "number of the slowest requests")
+		output  = flag.String("o", "text", "output format, text or json")
+		decrypt = flag.Bool("decrypt", false, "decrypt the files with -key")
+		keyFlag = flag.String("key", "", "AES key in hex or base64 of -decrypt, default is $"+tracing.EnvEncryptionKey)
+	)
+	flag.StringVar(&filter.App, "app", "", "LAPP of the entries")
+	flag.Var(&schema, "schema", "keys of the entries as name=key pairs, the names are time, level, message and caller")
+	flag.Var(&filter.Exprs, "where", "field expression, e.g. key=value, key!=value, key~regexp, key>number or key, may be repeated")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: logxstats [flags] file...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	now := time.Now()
+	var (
+		split time.Time
+		err   error
+	)
+	if *since != "" {
+		if filter.Since, err = logreader.ParseTimeArg(*since, now); err != nil {
+			usage(err)
+		}
+	}
+	if *until != "" {
+		if filter.Until, err = logreader.ParseTimeArg(*until, now); err != nil {
+			usage(err)
+		}
+	}
+	if *compare != "" {
+		if split, err = logreader.ParseTimeArg(*compare, now); err != nil {
+			usage(err)
+		}
+	}
+	if *bucket <= 0 {
+		usage(fmt.Errorf("invalid bucket %s", *bucket))
+	}
+	if *output != "text" && *output != "json" {
+		usage(fmt.Errorf("invalid output format %q", *output))
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := logreader.Options{Schema: schema}
+	if *decrypt {
+		s := *keyFlag
+		if s == "" {
+			s = os.Getenv(tracing.EnvEncryptionKey)
+		}
+		if opts.Key, err = tracing.ParseEncryptionKey(s); err != nil {
+			usage(err)
+		}
+	}
+
+	var files []string
+	for _, name := range flag.Args() {
+		fs, err := logreader.Files(name)
+		if err != nil {
+			fail(err)
+		}
+		files = append(files, fs...)
+	}
+
+	stats := logreader.NewStats(*bucket, *slow)
+	var baseline, current *logreader.Stats
+	if !split.IsZero() {
+		baseline = logreader.NewStats(*bucket, 0)
+		current = logreader.NewStats(*bucket, 0)
+	}
+	r := logreader.NewReader(files, opts)
+	defer r.Close()
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fail(err)
+		}
+		if !filter.Match(e) {
+			continue
+		}
+		stats.Add(e)
+		if split.IsZero() {
+			continue
+		}
+		if e.Time.Before(split) {
+			baseline.Add(e)
+		} else {
+			current.Add(e)
+		}
+	}
+
+	rep := newReport(stats, *top)
+	if !split.IsZero() {
+		rep.Compare = &comparison{
+			Split:             split,
+			Baseline:          newWindow(baseline),
+			Current:           newWindow(current),
+			NewErrorTemplates: current.NewErrorTemplates(baseline),
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if *output == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		err = enc.Encode(rep)
+	} else {
+		writeText(out, rep, *bucket)
+	}
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+type report struct {
+	window
+	TopMessages []*logreader.TemplateCount `json:"topMessages"`
+	Buckets     []bucket                   `json:"buckets"`
+	Slowest     []slowRequest              `json:"slowest"`
+	Statuses    []count                    `json:"statuses"`
+	RetCodes    []count                    `json:"retCodes"`
+	Compare     *comparison                `json:"compare,omitempty"`
+}
+
+type window struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Entries   int       `json:"entries"`
+	Errors    int       `json:"errors"`
+	ErrorRate float64   `json:"errorRate"`
+}
+
+type bucket struct {
+	logreader.Bucket
+	ErrorRate float64 `json:"errorRate"`
+}
+
+type slowRequest struct {
+	latency   time.Duration
+	Time      time.Time `json:"time"`
+	LatencyMs float64   `json:"latencyMs"`
+	Status    int       `json:"status,omitempty"`
+	App       string    `json:"app,omitempty"`
+	TraceID   string    `json:"traceId,omitempty"`
+	Request   string    `json:"request"`
+}
+
+type count struct {
+	Code  int `json:"code"`
+	Count int `json:"count"`
+}
+
+type comparison struct {
+	Split             time.Time                  `json:"split"`
+	Baseline          window                     `json:"baseline"`
+	Current           window                     `json:"current"`
+	NewErrorTemplates []*logreader.TemplateCount `json:"newErrorTemplates"`
+}
+
+func newWindow(s *logreader.Stats) window {
+	return window{
+		Start:     s.Start,
+		End:       s.End,
+		Entries:   s.Entries,
+		Errors:    s.Errors,
+		ErrorRate: logreader.Bucket{Entries: s.Entries, Errors: s.Errors}.ErrorRate(),
+	}
+}
+
+func newReport(s *logreader.Stats, top int) *report {
+	rep := &report{
+		window:      newWindow(s),
+		TopMessages: s.TopTemplates(top),
+		Statuses:    counts(s.Statuses),
+		RetCodes:    counts(s.RetCodes),
+	}
+	for _, b := range s.Buckets() {
+		rep.Buckets = append(rep.Buckets, bucket{Bucket: b, ErrorRate: b.ErrorRate()})
+	}
+	for _, e := range s.Slowest {
+		latency, _ := e.Latency()
+		rep.Slowest = append(rep.Slowest, slowRequest{
+			latency:   latency,
+			Time:      e.Time,
+			LatencyMs: float64(latency) / float64(time.Millisecond),
+			Status:    e.Status(),
+			App:       e.App,
+			TraceID:   e.TraceID,
+			Request:   request(e),
+		})
+	}
+	return rep
+}
+
+// request returns the method and URL of the request of an entry, or the
+// gRPC method, or the message.
+func request(e *logreader.Entry) string {
+	if url := e.FieldString("httpRequest.requestUrl"); url != "" {
+		return strings.TrimSpace(e.FieldString("httpRequest.requestMethod") + " " + url)
+	}
+	if method := e.FieldString("grpc.method"); method != "" {
+		return e.FieldString("grpc.service") + "/" + method
+	}
+	return e.Message
+}
+
+func counts(m map[int]int) []count {
+	cs := make([]count, 0, len(m))
+	for code, n := range m {
+		cs = append(cs, count{Code: code, Count: n})
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].Code < cs[j].Code })
+	return cs
+}
+
+func percent(rate float64) string {
+	return fmt.Sprintf("%.2f%%", rate*100)
+}
+
+func writeText(w io.Writer, rep *report, bucketSize time.Duration) {
+	fmt.Fprintf(w, "%d entries, %d errors (%s)", rep.Entries, rep.Errors, percent(rep.ErrorRate))
+	if !rep.Start.IsZero() {
+		fmt.Fprintf(w, " from %s to %s", rep.Start.Format(time.RFC3339), rep.End.Format(time.RFC3339))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "\nTOP MESSAGES\n")
+	fmt.Fprintf(w, "%8s %8s  %-6s %s\n", "COUNT", "ERRORS", "LEVEL", "TEMPLATE")
+	for _, tc := range rep.TopMessages {
+		fmt.Fprintf(w, "%8d %8d  %-6s %s\n", tc.Count, tc.Errors, tc.Level, tc.Template)
+	}
+
+	fmt.Fprintf(w, "\nERROR RATE (%s)\n", bucketSize)
+	for _, b := range rep.Buckets {
+		fmt.Fprintf(w, "%s %8d %8d %8s  %s\n", b.Start.Format(time.RFC3339), b.Entries, b.Errors,
+			percent(b.ErrorRate), strings.Repeat("#", int(b.ErrorRate*barWidth+0.5)))
+	}
+
+	if len(rep.Slowest) > 0 {
+		fmt.Fprintf(w, "\nSLOWEST REQUESTS\n")
+		for _, r := range rep.Slowest {
+			fmt.Fprintf(w, "%12s %4d  %s  %s", r.latency, r.Status, r.Time.Format(time.RFC3339), r.Request)
+			if r.App != "" {
+				fmt.Fprintf(w, "  app=%s", r.App)
+			}
+			if r.TraceID != "" {
+				fmt.Fprintf(w, "  trace=%s", r.TraceID)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	for _, d := range []struct {
+		name   string
+		counts []count
+	}{
+		{"STATUS", rep.Statuses},
+		{"RETCODE", rep.RetCodes},
+	} {
+		if len(d.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\n", d.name)
+		for _, c := range d.counts {
+			fmt.Fprintf(w, "%8d %8d\n", c.Code, c.Count)
+		}
+	}
+
+	if c := rep.Compare; c != nil {
+		fmt.Fprintf(w, "\nCOMPARE AT %s\n", c.Split.Format(time.RFC3339))
+		fmt.Fprintf(w, "baseline: %d entries, %d errors (%s)\n", c.Baseline.Entries, c.Baseline.Errors, percent(c.Baseline.ErrorRate))
+		fmt.Fprintf(w, "current:  %d entries, %d errors (%s)\n", c.Current.Entries, c.Current.Errors, percent(c.Current.ErrorRate))
+		fmt.Fprintf(w, "\nNEW ERROR TEMPLATES\n")
+		if len(c.NewErrorTemplates) == 0 {
+			fmt.Fprintln(w, "none")
+		}
+		for _, tc := range c.NewErrorTemplates {
+			fmt.Fprintf(w, "%8d  %s  first at %s, e.g. %s\n", tc.Errors, tc.Template, tc.First.Format(time.RFC3339), tc.Example)
+		}
+	}
+}
+
+func usage(err error) {
+	fmt.Fprintf(os.Stderr, "logxstats: %v\n", err)
+	os.Exit(2)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "logxstats: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/logreader"
+)
+
+// laneColors are the colors of the lanes, errors are red.
+var laneColors = []string{"#4e79a7", "#f28e2b", "#59a14f", "#b07aa1", "#76b7b2", "#edc948", "#9c755f", "#bab0ac"}
+
+const errorColor = "#e15759"
+
+// minBarWidth is the width in percent of an event without a latency.
+const minBarWidth = 0.4
+
+type htmlEvent struct {
+	logreader.Event
+	Gap     bool
+	GapText string
+	Offset  string
+	Left    float64
+	Width   float64
+	Color   string
+	Latency string
+	Status  int
+	Entry   string
+}
+
+type htmlPage struct {
+	*logreader.Timeline
+	Duration string
+	Start    string
+	Lanes    []htmlLane
+	Events   []htmlEvent
+}
+
+type htmlLane struct {
+	Name  string
+	Color string
+}
+
+// writeHTML writes the timeline as an HTML page without external resources,
+// a request is drawn as a bar of its latency ending at its entry.
+func writeHTML(w io.Writer, t *logreader.Timeline, gap time.Duration) error {
+	page := htmlPage{
+		Timeline: t,
+		Duration: t.Duration().String(),
+		Start:    t.Start.Format(time.RFC3339Nano),
+	}
+	for i, s := range t.Services {
+		page.Lanes = append(page.Lanes, htmlLane{Name: s, Color: laneColors[i%len(laneColors)]})
+	}
+
+	total := float64(t.Duration())
+	if total == 0 {
+		total = 1
+	}
+	for i, ev := range t.Events {
+		start := ev.Offset - ev.Latency
+		if start < 0 {
+			start = 0
+		}
+		he := htmlEvent{
+			Event:   ev,
+			Gap:     i > 0 && ev.Gap >= gap,
+			GapText: ev.Gap.String(),
+			Offset:  "+" + formatOffset(ev.Offset),
+			Left:    float64(start) / total * 100,
+			Width:   float64(ev.Offset-start) / total * 100,
+			Color:   laneColors[ev.Lane%len(laneColors)],
+			Status:  ev.Status(),
+			Entry:   string(ev.Raw),
+		}
+		if ev.Latency > 0 {
+			he.Latency = ev.Latency.String()
+		}
+		if he.Width < minBarWidth {
+			he.Width = minBarWidth
+		}
+		if he.Left+he.Width > 100 {
+			he.Left = 100 - he.Width
+		}
+		if ev.Error {
+			he.Color = errorColor
+		}
+		page.Events = append(page.Events, he)
+	}
+	return pageTemplate.Execute(w, page)
+}
+
+var pageTemplate = template.Must(template.New("trace").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>trace {{.TraceID}}</title>
+<style>
+body { font: 13px/1.4 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 24px; color: #222; }
+h1 { font-size: 18px; margin: 0 0 4px; }
+.summary { color: #666; margin-bottom: 12px; }
+.legend span { display: inline-block; margin-right: 12px; }
+.legend i { display: inline-block; width: 10px; height: 10px; margin-right: 4px; vertical-align: middle; }
+table { border-collapse: collapse; width: 100%; margin-top: 12px; }
+td { padding: 2px 6px; vertical-align: top; border-bottom: 1px solid #eee; white-space: nowrap; }
+td.offset { text-align: right; color: #666; font-family: monospace; }
+td.chart { width: 40%; position: relative; }
+td.chart div { position: absolute; top: 5px; height: 10px; border-radius: 2px; }
+td.message { white-space: normal; }
+tr.error td.message { color: #b00; }
+tr.gap td { text-align: center; color: #999; font-style: italic; background: #fafafa; }
+details pre { white-space: pre-wrap; word-break: break-all; margin: 4px 0; color: #444; }
+.meta { color: #888; margin-left: 6px; }
+</style>
+</head>
+<body>
+<h1>trace {{.TraceID}}</h1>
+<div class="summary">{{len .Events}} events, {{len .Services}} services, {{.Errors}} errors, {{.Duration}} from {{.Start}}</div>
+<div class="legend">{{range .Lanes}}<span><i style="background: {{.Color}}"></i>{{.Name}}</span>{{end}}</div>
+<table>
+{{range .Events}}{{if .Gap}}<tr class="gap"><td colspan="5">gap {{.GapText}}</td></tr>
+{{end}}<tr{{if .Error}} class="error"{{end}}>
+<td class="offset">{{.Offset}}</td>
+<td>{{.Service}}</td>
+<td>{{.Level}}</td>
+<td class="chart"><div style="left: {{printf "%.3f" .Left}}%; width: {{printf "%.3f" .Width}}%; background: {{.Color}}"></div></td>
+<td class="message"><details><summary>{{.Message}}{{if .Latency}}<span class="meta">latency {{.Latency}}</span>{{end}}{{if .Status}}<span class="meta">status {{.Status}}</span>{{end}}{{if .Caller}}<span class="meta">{{.Caller}}</span>{{end}}</summary><pre>{{.Entry}}</pre></details></td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
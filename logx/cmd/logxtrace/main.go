@@ -0,0 +1,244 @@
+// Command logxtrace reconstructs the timeline of a trace from the JSON log
+// files of several services.
+//
+//	logxtrace [flags] trace_id file...
+//
+// A file is a log file, which is read after its rotated backups, or a
+// directory of log files. The entries of the trace are merged by time, and
+// rendered with a lane per service, the gaps, the errors and the latencies
+// of the requests, as text, JSON or a self-contained HTML page:
+//
+//	logxtrace -o html 4bf92f3577b34da6 order/logs payment/logs > trace.html
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/logreader"
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+func main() {
+	var (
+		schema  = logreader.DefaultSchema
+		output  = flag.String("o", "text", "output format, text, json or html")
+		gap     = flag.Duration("gap", 100*time.Millisecond, "minimum time between two events to mark a gap")
+		decrypt = flag.Bool("decrypt", false, "decrypt the files with -key")
+		keyFlag = flag.String("key", "", "AES key in hex or base64 of -decrypt, default is $"+tracing.EnvEncryptionKey)
+	)
+	flag.Var(&schema, "schema", "keys of the entries as name=key pairs, the names are time, level, message and caller")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: logxtrace [flags] trace_id file...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	render, ok := map[string]func(io.Writer, *logreader.Timeline, time.Duration) error{
+		"text": writeText,
+		"json": writeJSON,
+		"html": writeHTML,
+	}[*output]
+	if !ok {
+		usage(fmt.Errorf("invalid output format %q", *output))
+	}
+
+	opts := logreader.Options{Schema: schema}
+	if *decrypt {
+		s := *keyFlag
+		if s == "" {
+			s = os.Getenv(tracing.EnvEncryptionKey)
+		}
+		key, err := tracing.ParseEncryptionKey(s)
+		if err != nil {
+			usage(err)
+		}
+		opts.Key = key
+	}
+
+	traceID := flag.Arg(0)
+	var files []string
+	for _, name := range flag.Args()[1:] {
+		fs, err := logreader.Files(name)
+		if err != nil {
+			fail(err)
+		}
+		files = append(files, fs...)
+	}
+
+	r := logreader.NewReader(files, opts)
+	defer r.Close()
+	filter := logreader.Filter{TraceID: traceID}
+	var entries []*logreader.Entry
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fail(err)
+		}
+		if filter.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	t := logreader.NewTimeline(traceID, entries)
+	if len(t.Events) == 0 {
+		fail(fmt.Errorf("no entries of trace %s", traceID))
+	}
+	out := bufio.NewWriter(os.Stdout)
+	if err := render(out, t, *gap); err != nil {
+		fail(err)
+	}
+	if err := out.Flush(); err != nil {
+		fail(err)
+	}
+}
+
+// writeText writes the timeline with a column per lane, a lane is drawn from
+// the first to the last event of its service.
+func writeText(w io.Writer, t *logreader.Timeline, gap time.Duration) error {
+	fmt.Fprintf(w, "trace %s: %d events, %d services, %d errors, %s from %s\n\n",
+		t.TraceID, len(t.Events), len(t.Services), t.Errors, t.Duration(), t.Start.Format(time.RFC3339Nano))
+
+	first := make([]int, len(t.Services))
+	last := make([]int, len(t.Services))
+	for i := range first {
+		first[i] = -1
+	}
+	for i, ev := range t.Events {
+		if first[ev.Lane] < 0 {
+			first[ev.Lane] = i
+		}
+		last[ev.Lane] = i
+	}
+
+	fmt.Fprintf(w, "%10s ", "OFFSET")
+	for _, s := range t.Services {
+		fmt.Fprintf(w, " %s", s)
+	}
+	fmt.Fprintln(w)
+
+	for i, ev := range t.Events {
+		if i > 0 && ev.Gap >= gap {
+			fmt.Fprintf(w, "%10s  ~ gap %s ~\n", "", ev.Gap)
+		}
+		fmt.Fprintf(w, "%10s ", "+"+formatOffset(ev.Offset))
+		for lane, s := range t.Services {
+			mark := " "
+			switch {
+			case lane == ev.Lane && ev.Error:
+				mark = "!"
+			case lane == ev.Lane:
+				mark = "*"
+			case first[lane] < i && i < last[lane]:
+				mark = "|"
+			}
+			// the mark is at the center of the name of the lane
+			width := len(s)
+			if width == 0 {
+				width = 1
+			}
+			pad := (width - 1) / 2
+			fmt.Fprintf(w, " %s%s%s", strings.Repeat(" ", pad), mark, strings.Repeat(" ", width-1-pad))
+		}
+		fmt.Fprintf(w, "  %-5s %s: %s", ev.Level, ev.Service, ev.Message)
+		if ev.Latency > 0 {
+			fmt.Fprintf(w, " latency=%s", ev.Latency)
+		}
+		if status := ev.Status(); status != 0 {
+			fmt.Fprintf(w, " status=%d", status)
+		}
+		if ev.Caller != "" {
+			fmt.Fprintf(w, " (%s)", ev.Caller)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func formatOffset(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
+
+type jsonTimeline struct {
+	TraceID    string      `json:"traceId"`
+	Start      time.Time   `json:"start"`
+	End        time.Time   `json:"end"`
+	DurationMs float64     `json:"durationMs"`
+	Services   []string    `json:"services"`
+	Errors     int         `json:"errors"`
+	Events     []jsonEvent `json:"events"`
+}
+
+type jsonEvent struct {
+	Time      time.Time       `json:"time"`
+	OffsetMs  float64         `json:"offsetMs"`
+	GapMs     float64         `json:"gapMs"`
+	Gap       bool            `json:"gap,omitempty"`
+	Service   string          `json:"service"`
+	Lane      int             `json:"lane"`
+	Level     string          `json:"level"`
+	Message   string          `json:"message"`
+	Caller    string          `json:"caller,omitempty"`
+	LatencyMs float64         `json:"latencyMs,omitempty"`
+	Status    int             `json:"status,omitempty"`
+	Error     bool            `json:"error,omitempty"`
+	Entry     json.RawMessage `json:"entry"`
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func writeJSON(w io.Writer, t *logreader.Timeline, gap time.Duration) error {
+	jt := jsonTimeline{
+		TraceID:    t.TraceID,
+		Start:      t.Start,
+		End:        t.End,
+		DurationMs: milliseconds(t.Duration()),
+		Services:   t.Services,
+		Errors:     t.Errors,
+	}
+	for i, ev := range t.Events {
+		jt.Events = append(jt.Events, jsonEvent{
+			Time:      ev.Time,
+			OffsetMs:  milliseconds(ev.Offset),
+			GapMs:     milliseconds(ev.Gap),
+			Gap:       i > 0 && ev.Gap >= gap,
+			Service:   ev.Service,
+			Lane:      ev.Lane,
+			Level:     ev.Level,
+			Message:   ev.Message,
+			Caller:    ev.Caller,
+			LatencyMs: milliseconds(ev.Latency),
+			Status:    ev.Status(),
+			Error:     ev.Error,
+			Entry:     ev.Raw,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jt)
+}
+
+func usage(err error) {
+	fmt.Fprintf(os.Stderr, "logxtrace: %v\n", err)
+	os.Exit(2)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "logxtrace: %v\n", err)
+	os.Exit(1)
+}
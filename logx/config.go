@@ -2,6 +2,8 @@ package logx
 
 import (
 	"github.com/kakabei/kfgolib/logx/tracing"
+
+	"go.uber.org/zap/zapcore"
 )
 
 type Config tracing.Config
@@ -30,3 +32,59 @@ func WithGlobalCallerSkip(n int) Option {
 		c.GlobalCallerSkip = n
 	}
 }
+
+// WithHooks registers hooks which are called asynchronously with every entry.
+func WithHooks(hooks ...tracing.Hook) Option {
+	return Option(tracing.WithHooks(hooks...))
+}
+
+// WithHookLevel sets the minimum level of entries passed to hooks.
+func WithHookLevel(level string) Option {
+	return Option(tracing.WithHookLevel(level))
+}
+
+// WithStacktraceLevel attaches a stacktrace to entries at or above level.
+func WithStacktraceLevel(level string) Option {
+	return Option(tracing.WithStacktraceLevel(level))
+}
+
+// WithWrapCore wraps or replaces the core built from the config.
+func WithWrapCore(f func(zapcore.Core) zapcore.Core) Option {
+	return Option(tracing.WithWrapCore(f))
+}
+
+// WithGolden makes the output deterministic, see tracing.Config.Golden.
+func WithGolden() Option {
+	return Option(tracing.WithGolden())
+}
+
+// WithTimeFormat sets the format and the time zone of the time, see
+// tracing.Config.TimeFormat and tracing.Config.TimeZone.
+func WithTimeFormat(format string, zone string) Option {
+	return Option(tracing.WithTimeFormat(format, zone))
+}
+
+// SinkConfig is an output of the logger, see tracing.SinkConfig.
+type SinkConfig = tracing.SinkConfig
+
+// WithSinks adds outputs to the logger, see tracing.Config.Sinks.
+func WithSinks(sinks ...SinkConfig) Option {
+	return Option(tracing.WithSinks(sinks...))
+}
+
+// WithCrashFile writes the crash reports to the file, see
+// tracing.Config.CrashFile.
+func WithCrashFile(filename string) Option {
+	return Option(tracing.WithCrashFile(filename))
+}
+
+// WithAudit enables the audit log in the file, see tracing.Config.EnableAudit.
+func WithAudit(filename string, key string) Option {
+	return Option(tracing.WithAudit(filename, key))
+}
+
+// WithDiskGuard sets the minimum free space and the maximum total size in
+// megabytes of the log file, see tracing.SinkConfig.MinFreeSpace.
+func WithDiskGuard(minFreeSpace int, maxTotalSize int) Option {
+	return Option(tracing.WithDiskGuard(minFreeSpace, maxTotalSize))
+}
@@ -0,0 +1,29 @@
+// Package expvarx publishes the status of the loggers of logx as expvars.
+// Importing it, like expvar, registers /debug/vars on http.DefaultServeMux,
+// which also shows the command line and the memory statistics.
+package expvarx
+
+import (
+	"expvar"
+	"sync"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+var once sync.Once
+
+// Publish publishes tracing.FileSinkStatuses as the expvar logx_file_sinks
+// and tracing.QueueStatuses as logx_queues. It may be called more than once,
+// a name which is already published is skipped.
+func Publish() {
+	once.Do(func() {
+		publish("logx_file_sinks", func() interface{} { return tracing.FileSinkStatuses() })
+		publish("logx_queues", func() interface{} { return tracing.QueueStatuses() })
+	})
+}
+
+func publish(name string, f expvar.Func) {
+	if expvar.Get(name) == nil {
+		expvar.Publish(name, f)
+	}
+}
@@ -0,0 +1,27 @@
+package expvarx_test
+
+import (
+	"expvar"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/expvarx"
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+func TestPublish(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	logger := tracing.NewLogger(tracing.Config{AppName: "logx_test"},
+		tracing.WithSinks(tracing.SinkConfig{Type: "file", Filename: filename, DegradedLevel: "warn"}))
+	defer logger.Close()
+
+	expvarx.Publish()
+	expvarx.Publish()
+	if v := expvar.Get("logx_file_sinks"); v == nil || !strings.Contains(v.String(), filepath.Base(filename)) {
+		t.Errorf("unexpected expvar %v", v)
+	}
+	if v := expvar.Get("logx_queues"); v == nil || v.String() != "[]" {
+		t.Errorf("unexpected expvar %v", v)
+	}
+}
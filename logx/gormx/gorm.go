@@ -0,0 +1,115 @@
+// Package gormx provides a gorm logger.Interface which logs through logx.
+package gormx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx"
+	"go.uber.org/zap"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// Config configures the gorm logger.
+type Config struct {
+	// SlowThreshold logs queries taking it or longer at level Warn, zero disables
+	// slow query logging.
+	SlowThreshold time.Duration
+
+	// IgnoreRecordNotFoundError determines if gorm.ErrRecordNotFound is
+	// logged as an error.
+	IgnoreRecordNotFoundError bool
+
+	// LogLevel is the gorm log level. Queries are logged at level Debug when
+	// it is logger.Info.
+	LogLevel gormlogger.LogLevel
+}
+
+// NewConfig returns the default config, queries slower than 200ms are logged.
+func NewConfig() Config {
+	return Config{
+		SlowThreshold:             200 * time.Millisecond,
+		IgnoreRecordNotFoundError: true,
+		LogLevel:                  gormlogger.Warn,
+	}
+}
+
+type logger struct {
+	log    *logx.VLogger
+	config Config
+}
+
+// New returns a gorm logger which logs on l, or on the global logger at the
+// time of each query if l is nil, so it follows SetConfig and
+// ReplaceLogger. The trace ID is taken from the context of each query.
+func New(l *logx.VLogger, config Config) gormlogger.Interface {
+	if l == nil {
+		return &logger{config: config}
+	}
+	return &logger{log: l.AddCallerSkip(1), config: config}
+}
+
+// vlog returns the logger of the queries.
+func (l *logger) vlog() *logx.VLogger {
+	if l.log != nil {
+		return l.log
+	}
+	return logx.AddCallerSkip(1)
+}
+
+func (l *logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	c := *l
+	c.config.LogLevel = level
+	return &c
+}
+
+func (l *logger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= gormlogger.Info {
+		l.vlog().InfofContext(ctx, msg, data...)
+	}
+}
+
+func (l *logger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= gormlogger.Warn {
+		l.vlog().WarnfContext(ctx, msg, data...)
+	}
+}
+
+func (l *logger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= gormlogger.Error {
+		l.vlog().ErrorfContext(ctx, msg, data...)
+	}
+}
+
+func (l *logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.config.LogLevel <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	fields := func() []zap.Field {
+		sql, rows := fc()
+		return []zap.Field{
+			zap.String("sql", sql),
+			zap.Int64("rows", rows),
+			zap.Duration("elapsed", elapsed),
+			zap.String("sqlfile", utils.FileWithLineNum()),
+		}
+	}
+
+	switch {
+	case err != nil && l.config.LogLevel >= gormlogger.Error &&
+		!(l.config.IgnoreRecordNotFoundError && errors.Is(err, gormlogger.ErrRecordNotFound)):
+		l.vlog().ErrContext(ctx, err, "sql error", fields()...)
+	case l.config.SlowThreshold != 0 && elapsed >= l.config.SlowThreshold && l.config.LogLevel >= gormlogger.Warn:
+		msg := fmt.Sprintf("slow sql >= %v", l.config.SlowThreshold)
+		l.vlog().ErrAtContext(ctx, "warn", nil, msg, fields()...)
+	case l.config.LogLevel >= gormlogger.Info:
+		if log := l.vlog(); log.Enabled("debug") {
+			log.ErrAtContext(ctx, "debug", nil, "sql", fields()...)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package gormx_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx"
+	"github.com/kakabei/kfgolib/logx/gormx"
+	"github.com/kakabei/kfgolib/logx/logxtest"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestTrace(t *testing.T) {
+	l, logs := logxtest.New(logx.WithGlobalCallerSkip(1))
+	log := gormx.New(l, gormx.NewConfig())
+	ctx := logx.NewTraceCtx("gorm-trace")
+	sql := func() (string, int64) { return "SELECT * FROM users", 2 }
+
+	log.Trace(ctx, time.Now(), sql, nil)
+	log.Trace(ctx, time.Now().Add(-time.Second), sql, nil)
+	log.Trace(ctx, time.Now(), sql, gormlogger.ErrRecordNotFound)
+	log.Trace(ctx, time.Now(), sql, errors.New("deadlock"))
+	log.LogMode(gormlogger.Info).Trace(ctx, time.Now(), sql, nil)
+	log.LogMode(gormlogger.Silent).Trace(ctx, time.Now(), sql, errors.New("silent"))
+
+	entries := logs.FilterTraceID("gorm-trace").All()
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	for i, level := range []string{"warn", "error", "debug"} {
+		fields := entries[i].ContextMap()
+		if entries[i].Level.String() != level || fields["sql"] != "SELECT * FROM users" || fields["rows"] != int64(2) {
+			t.Errorf("unexpected entry %v %v", entries[i].Entry, fields)
+		}
+	}
+	if entries[1].ContextMap()["error"] == nil {
+		t.Errorf("sql error has no error field")
+	}
+}
+
+func TestGlobal(t *testing.T) {
+	// created before the global logger is replaced
+	log := gormx.New(nil, gormx.NewConfig())
+	logs := logxtest.ReplaceGlobal(t)
+
+	sql := func() (string, int64) { return "SELECT 1", 1 }
+	log.Trace(logx.NewTraceCtx("gorm-global"), time.Now().Add(-time.Second), sql, nil)
+
+	entries := logs.FilterTraceID("gorm-global").All()
+	if len(entries) != 1 || entries[0].Message != "slow sql >= 200ms" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+	if filepath.Base(entries[0].Caller.File) != "gorm_test.go" {
+		t.Errorf("caller is %s, want gorm_test.go", entries[0].Caller.File)
+	}
+}
@@ -0,0 +1,112 @@
+package grpcx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor returns an interceptor which injects the trace of
+// the context into the outgoing metadata and logs every RPC.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		start := time.Now()
+		var p peer.Peer
+		err := invoker(injectTrace(ctx), method, req, reply, cc, append(callOpts, grpc.Peer(&p))...)
+
+		code := status.Code(err)
+		fields := methodFields(method, "unary")
+		if p.Addr != nil {
+			fields = append(fields, zap.String("peer.address", p.Addr.String()))
+		}
+		fields = append(fields, resultFields(code, start)...)
+		fields = append(fields,
+			zap.Int("grpc.request_size", messageSize(req)),
+			zap.Int("grpc.response_size", messageSize(reply)),
+		)
+		o.log(ctx, code, err, "grpc client", fields...)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor which injects the trace of
+// the context into the outgoing metadata and logs every RPC when the stream
+// finishes, that is when RecvMsg returns an error or io.EOF.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		kind := "bidi_stream"
+		if !desc.ClientStreams {
+			kind = "server_stream"
+		} else if !desc.ServerStreams {
+			kind = "client_stream"
+		}
+
+		stream := &clientStream{
+			o:      o,
+			ctx:    ctx,
+			start:  time.Now(),
+			fields: methodFields(method, kind),
+		}
+		cs, err := streamer(injectTrace(ctx), desc, cc, method, callOpts...)
+		if err != nil {
+			stream.finish(err)
+			return nil, err
+		}
+		stream.ClientStream = cs
+		return stream, nil
+	}
+}
+
+// clientStream counts the messages and logs the RPC when it finishes.
+type clientStream struct {
+	grpc.ClientStream
+	messageCounter
+
+	o      *options
+	ctx    context.Context
+	start  time.Time
+	fields []zap.Field
+	once   sync.Once
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.sent(m)
+	} else if !errors.Is(err, io.EOF) {
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		s.received(m)
+		return nil
+	}
+	if errors.Is(err, io.EOF) {
+		s.finish(nil)
+	} else {
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		code := status.Code(err)
+		fields := append(s.fields, resultFields(code, s.start)...)
+		fields = append(fields, s.messageCounter.fields(true)...)
+		s.o.log(s.ctx, code, err, "grpc client", fields...)
+	})
+}
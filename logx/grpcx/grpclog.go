@@ -0,0 +1,68 @@
+package grpcx
+
+import (
+	"fmt"
+
+	"github.com/kakabei/kfgolib/logx"
+	"google.golang.org/grpc/grpclog"
+)
+
+// logger implements grpclog.LoggerV2 and grpclog.DepthLoggerV2.
+type logger struct {
+	base      *logx.VLogger
+	log       *logx.VLogger
+	verbosity int
+}
+
+// NewLoggerV2 returns a grpclog.LoggerV2 which logs on l, verbose logs are
+// enabled up to verbosity. Use it with grpclog.SetLoggerV2, the caller is
+// the caller of the grpclog package functions.
+func NewLoggerV2(l *logx.VLogger, verbosity int) grpclog.LoggerV2 {
+	return &logger{base: l, log: l.AddCallerSkip(2), verbosity: verbosity}
+}
+
+func (l *logger) Info(args ...interface{})                 { l.log.Info(args...) }
+func (l *logger) Infoln(args ...interface{})               { l.log.Info(sprintln(args)) }
+func (l *logger) Infof(format string, args ...interface{}) { l.log.Infof(format, args...) }
+
+func (l *logger) Warning(args ...interface{})                 { l.log.Warn(args...) }
+func (l *logger) Warningln(args ...interface{})               { l.log.Warn(sprintln(args)) }
+func (l *logger) Warningf(format string, args ...interface{}) { l.log.Warnf(format, args...) }
+
+func (l *logger) Error(args ...interface{})                 { l.log.Error(args...) }
+func (l *logger) Errorln(args ...interface{})               { l.log.Error(sprintln(args)) }
+func (l *logger) Errorf(format string, args ...interface{}) { l.log.Errorf(format, args...) }
+
+func (l *logger) Fatal(args ...interface{})                 { l.log.Fatal(args...) }
+func (l *logger) Fatalln(args ...interface{})               { l.log.Fatal(sprintln(args)) }
+func (l *logger) Fatalf(format string, args ...interface{}) { l.log.Fatalf(format, args...) }
+
+func (l *logger) V(level int) bool {
+	return level <= l.verbosity
+}
+
+// InfoDepth implements grpclog.DepthLoggerV2 interface.
+func (l *logger) InfoDepth(depth int, args ...interface{}) {
+	l.base.AddCallerSkip(depth + 2).Info(sprintln(args))
+}
+
+// WarningDepth implements grpclog.DepthLoggerV2 interface.
+func (l *logger) WarningDepth(depth int, args ...interface{}) {
+	l.base.AddCallerSkip(depth + 2).Warn(sprintln(args))
+}
+
+// ErrorDepth implements grpclog.DepthLoggerV2 interface.
+func (l *logger) ErrorDepth(depth int, args ...interface{}) {
+	l.base.AddCallerSkip(depth + 2).Error(sprintln(args))
+}
+
+// FatalDepth implements grpclog.DepthLoggerV2 interface.
+func (l *logger) FatalDepth(depth int, args ...interface{}) {
+	l.base.AddCallerSkip(depth + 2).Fatal(sprintln(args))
+}
+
+// sprintln formats like fmt.Sprintln without the trailing newline.
+func sprintln(args []interface{}) string {
+	s := fmt.Sprintln(args...)
+	return s[:len(s)-1]
+}
@@ -0,0 +1,36 @@
+package grpcx_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx"
+	"github.com/kakabei/kfgolib/logx/grpcx"
+	"github.com/kakabei/kfgolib/logx/logxtest"
+	"google.golang.org/grpc/grpclog"
+)
+
+func TestLoggerV2(t *testing.T) {
+	l, logs := logxtest.New(logx.WithGlobalCallerSkip(1))
+	grpclog.SetLoggerV2(grpcx.NewLoggerV2(l, 1))
+
+	grpclog.Infof("dialing %s", "bufnet")
+	grpclog.Warning("transport closing")
+	grpclog.Component("test").Errorln("connection", "reset")
+
+	entries := logs.All()
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	if entries[0].Message != "dialing bufnet" || entries[1].Level.String() != "warn" || entries[2].Message != "[test] connection reset" {
+		t.Errorf("unexpected entries %v", entries)
+	}
+	for _, e := range entries {
+		if filepath.Base(e.Caller.File) != "grpclog_test.go" {
+			t.Errorf("caller of %q is %s, want grpclog_test.go", e.Message, e.Caller.File)
+		}
+	}
+	if logger := grpcx.NewLoggerV2(l, 1); !logger.V(1) || logger.V(2) {
+		t.Errorf("unexpected verbosity")
+	}
+}
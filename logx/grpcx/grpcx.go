@@ -0,0 +1,144 @@
+// Package grpcx provides gRPC interceptors which propagate the trace ID and
+// log every RPC through logx.
+package grpcx
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx"
+	"github.com/kakabei/kfgolib/logx/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// metadata keys are always lower case.
+	keyTraceparent = "traceparent"
+)
+
+type options struct {
+	logger    *logx.VLogger
+	levelFunc func(codes.Code) string
+}
+
+// Option configures the interceptors.
+type Option func(*options)
+
+// WithLogger sets the logger, the default is the global logger.
+func WithLogger(l *logx.VLogger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithLevelFunc sets the function which chooses the log level from the
+// status code, the default is DefaultLevel.
+func WithLevelFunc(f func(codes.Code) string) Option {
+	return func(o *options) {
+		o.levelFunc = f
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{levelFunc: DefaultLevel}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// DefaultLevel logs OK at level Info, errors caused by the client at level
+// Warn and server errors at level Error.
+func DefaultLevel(code codes.Code) string {
+	switch code {
+	case codes.OK:
+		return "info"
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+func (o *options) log(ctx context.Context, code codes.Code, err error, msg string, fields ...zap.Field) {
+	level := o.levelFunc(code)
+	if o.logger != nil {
+		o.logger.ErrAtContext(ctx, level, err, msg, fields...)
+		return
+	}
+	logx.ErrAtContext(ctx, level, err, msg, fields...)
+}
+
+// extractTrace returns a context carrying the trace of the incoming metadata.
+// The traceparent is used if valid, its trace ID is also the one of
+// GetTraceID, then the X-Request-ID, and a new trace ID is generated if
+// there is neither.
+func extractTrace(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(keyTraceparent); len(v) > 0 {
+		if sc, err := tracing.ParseTraceparent(v[0]); err == nil {
+			ctx = logx.WithTraceID(ctx, sc.TraceID().String())
+			return trace.ContextWithRemoteSpanContext(ctx, sc)
+		}
+	}
+	if v := md.Get(strings.ToLower(tracing.KeyXRequestID)); len(v) > 0 && v[0] != "" {
+		return logx.WithTraceID(ctx, v[0])
+	}
+	return logx.WithTraceID(ctx, tracing.NewTraceID())
+}
+
+// injectTrace returns a context whose outgoing metadata carries the trace ID
+// and traceparent of ctx.
+func injectTrace(ctx context.Context) context.Context {
+	kvs := []string{}
+	if traceID := logx.GetTraceID(ctx); traceID != "" {
+		kvs = append(kvs, strings.ToLower(tracing.KeyXRequestID), traceID)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		kvs = append(kvs, keyTraceparent, tracing.FormatTraceparent(sc))
+	}
+	if len(kvs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kvs...)
+}
+
+func methodFields(fullMethod string, kind string) []zap.Field {
+	service, method := path.Split(fullMethod)
+	return []zap.Field{
+		zap.String("grpc.service", strings.Trim(service, "/")),
+		zap.String("grpc.method", method),
+		zap.String("grpc.kind", kind),
+	}
+}
+
+func peerField(ctx context.Context) zap.Field {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return zap.String("peer.address", p.Addr.String())
+	}
+	return zap.Skip()
+}
+
+func resultFields(code codes.Code, start time.Time) []zap.Field {
+	return []zap.Field{
+		zap.String("grpc.code", code.String()),
+		zap.Duration("grpc.latency", time.Since(start)),
+	}
+}
+
+func messageSize(m interface{}) int {
+	if pm, ok := m.(proto.Message); ok {
+		return proto.Size(pm)
+	}
+	return 0
+}
@@ -0,0 +1,181 @@
+package grpcx_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx"
+	"github.com/kakabei/kfgolib/logx/grpcx"
+	"github.com/kakabei/kfgolib/logx/logxtest"
+	"github.com/kakabei/kfgolib/logx/tracing"
+	"go.opentelemetry.io/otel/trace"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthServer panics for the service "panic", logs the trace ID of the
+// context for the service "trace" and returns NotFound for unknown services.
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	logger *logx.VLogger
+}
+
+func (s *healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	s.logger.InfoContext(ctx, "checking "+req.Service)
+	switch req.Service {
+	case "":
+		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+	case "panic":
+		panic("check panic")
+	case "trace":
+		s.logger.InfoContext(ctx, "trace ID "+logx.GetTraceID(ctx))
+		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+	default:
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+}
+
+func (s *healthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newClient(t *testing.T) (grpc_health_v1.HealthClient, *logxtest.Logs, *logxtest.Logs) {
+	serverLogger, serverLogs := logxtest.New()
+	clientLogger, clientLogs := logxtest.New()
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(grpcx.UnaryServerInterceptor(grpcx.WithLogger(serverLogger))),
+		grpc.StreamInterceptor(grpcx.StreamServerInterceptor(grpcx.WithLogger(serverLogger))),
+	)
+	grpc_health_v1.RegisterHealthServer(server, &healthServer{logger: serverLogger})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(grpcx.UnaryClientInterceptor(grpcx.WithLogger(clientLogger))),
+		grpc.WithStreamInterceptor(grpcx.StreamClientInterceptor(grpcx.WithLogger(clientLogger))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn), serverLogs, clientLogs
+}
+
+func TestUnary(t *testing.T) {
+	client, serverLogs, clientLogs := newClient(t)
+	ctx := logx.NewTraceCtx("grpc-trace")
+
+	if _, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "missing"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("got %v, want NotFound", err)
+	}
+	if _, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "panic"}); status.Code(err) != codes.Internal {
+		t.Fatalf("got %v, want Internal", err)
+	}
+
+	// the handler and the interceptor log with the propagated trace ID.
+	if n := serverLogs.FilterTraceID("grpc-trace").Len(); n != 6 {
+		t.Errorf("got %d server entries with the trace ID, want 6", n)
+	}
+
+	rpcs := serverLogs.FilterMessage("grpc server").All()
+	if len(rpcs) != 3 {
+		t.Fatalf("got %d server entries, want 3", len(rpcs))
+	}
+	for i, want := range []struct {
+		level string
+		code  string
+	}{{"info", "OK"}, {"warn", "NotFound"}, {"error", "Internal"}} {
+		fields := rpcs[i].ContextMap()
+		if rpcs[i].Level.String() != want.level || fields["grpc.code"] != want.code {
+			t.Errorf("unexpected entry %v %v", rpcs[i].Level, fields)
+		}
+		if fields["grpc.service"] != "grpc.health.v1.Health" || fields["grpc.method"] != "Check" {
+			t.Errorf("unexpected method %v", fields)
+		}
+	}
+	if fields := rpcs[2].ContextMap(); fields["panic"] != "check panic" || fields["stacktrace"] == nil {
+		t.Errorf("panic is not logged: %v", fields)
+	}
+	if n := serverLogs.FilterFieldKey("peer.address").FilterMessage("grpc server").Len(); n != 3 {
+		t.Errorf("got %d entries with peer.address, want 3", n)
+	}
+
+	if n := clientLogs.FilterMessage("grpc client").FilterTraceID("grpc-trace").Len(); n != 3 {
+		t.Errorf("got %d client entries, want 3", n)
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	client, serverLogs, _ := newClient(t)
+	sc, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the client sends the traceparent only
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), sc)
+	if _, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "trace"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := serverLogs.FilterMessage("trace ID 4bf92f3577b34da6a3ce929d0e0e4736").Len(); n != 1 {
+		t.Errorf("unexpected server entries %v", serverLogs.All())
+	}
+}
+
+func TestStream(t *testing.T) {
+	client, serverLogs, clientLogs := newClient(t)
+
+	stream, err := client.Watch(logx.NewTraceCtx("stream-trace"), &grpc_health_v1.HealthCheckRequest{Service: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, logs := range []*logxtest.Logs{serverLogs, clientLogs} {
+		entries := logs.FilterTraceID("stream-trace").All()
+		if len(entries) != 1 {
+			t.Fatalf("got %d entries, want 1", len(entries))
+		}
+		fields := entries[0].ContextMap()
+		if fields["grpc.kind"] != "server_stream" || fields["grpc.code"] != "OK" {
+			t.Errorf("unexpected entry %v", fields)
+		}
+	}
+	if fields := serverLogs.All()[0].ContextMap(); fields["grpc.sent_msgs"] != int64(3) || fields["grpc.recv_msgs"] != int64(1) {
+		t.Errorf("unexpected message counts %v", fields)
+	}
+	if fields := clientLogs.All()[0].ContextMap(); fields["grpc.sent_msgs"] != int64(1) || fields["grpc.recv_msgs"] != int64(3) {
+		t.Errorf("unexpected message counts %v", fields)
+	}
+	// the request of 3 bytes and the responses of 2 bytes on both sides
+	for _, logs := range []*logxtest.Logs{serverLogs, clientLogs} {
+		if fields := logs.All()[0].ContextMap(); fields["grpc.request_size"] != int64(3) || fields["grpc.response_size"] != int64(6) {
+			t.Errorf("unexpected sizes %v", fields)
+		}
+	}
+}
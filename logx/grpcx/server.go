@@ -0,0 +1,142 @@
+package grpcx
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns an interceptor which extracts the trace from
+// the incoming metadata into the context, recovers panics and logs every RPC.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx = extractTrace(ctx)
+		start := time.Now()
+		var panicFields []zap.Field
+
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					panicFields = []zap.Field{zap.Any("panic", v), zap.Stack("stacktrace")}
+					err = status.Errorf(codes.Internal, "panic: %v", v)
+				}
+			}()
+			resp, err = handler(ctx, req)
+		}()
+
+		code := status.Code(err)
+		fields := methodFields(info.FullMethod, "unary")
+		fields = append(fields, peerField(ctx))
+		fields = append(fields, resultFields(code, start)...)
+		fields = append(fields,
+			zap.Int("grpc.request_size", messageSize(req)),
+			zap.Int("grpc.response_size", messageSize(resp)),
+		)
+		fields = append(fields, panicFields...)
+		o.log(ctx, code, err, "grpc server", fields...)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor which extracts the trace from
+// the incoming metadata into the stream context, recovers panics and logs
+// every RPC with the number and size of the messages.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		stream := &serverStream{ServerStream: ss, ctx: extractTrace(ss.Context())}
+		start := time.Now()
+		var panicFields []zap.Field
+
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					panicFields = []zap.Field{zap.Any("panic", v), zap.Stack("stacktrace")}
+					err = status.Errorf(codes.Internal, "panic: %v", v)
+				}
+			}()
+			err = handler(srv, stream)
+		}()
+
+		kind := "bidi_stream"
+		if !info.IsClientStream {
+			kind = "server_stream"
+		} else if !info.IsServerStream {
+			kind = "client_stream"
+		}
+
+		code := status.Code(err)
+		fields := methodFields(info.FullMethod, kind)
+		fields = append(fields, peerField(stream.ctx))
+		fields = append(fields, resultFields(code, start)...)
+		fields = append(fields, stream.fields(false)...)
+		fields = append(fields, panicFields...)
+		o.log(stream.ctx, code, err, "grpc server", fields...)
+		return err
+	}
+}
+
+// serverStream replaces the context of the stream and counts the messages.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	messageCounter
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent(m)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received(m)
+	}
+	return err
+}
+
+// messageCounter counts the number and size of the messages of a stream.
+// SendMsg and RecvMsg may be called by different goroutines.
+type messageCounter struct {
+	recvMsgs, sentMsgs int64
+	recvSize, sentSize int64
+}
+
+func (c *messageCounter) sent(m interface{}) {
+	atomic.AddInt64(&c.sentMsgs, 1)
+	atomic.AddInt64(&c.sentSize, int64(messageSize(m)))
+}
+
+func (c *messageCounter) received(m interface{}) {
+	atomic.AddInt64(&c.recvMsgs, 1)
+	atomic.AddInt64(&c.recvSize, int64(messageSize(m)))
+}
+
+// fields returns the counts, the requests are the received messages of a
+// server and the sent messages of a client.
+func (c *messageCounter) fields(client bool) []zap.Field {
+	requestSize, responseSize := atomic.LoadInt64(&c.recvSize), atomic.LoadInt64(&c.sentSize)
+	if client {
+		requestSize, responseSize = responseSize, requestSize
+	}
+	return []zap.Field{
+		zap.Int64("grpc.recv_msgs", atomic.LoadInt64(&c.recvMsgs)),
+		zap.Int64("grpc.sent_msgs", atomic.LoadInt64(&c.sentMsgs)),
+		zap.Int64("grpc.request_size", requestSize),
+		zap.Int64("grpc.response_size", responseSize),
+	}
+}
@@ -3,8 +3,10 @@ package logx
 import (
 	"context"
 	"fmt"
+	"log"
+	"net/http"
 
-	"github.com/kakabei/kgo-public/logx/tracing"
+	"github.com/kakabei/kfgolib/logx/tracing"
 
 	"go.uber.org/zap"
 )
@@ -26,6 +28,49 @@ func GetIP(eth string) string {
 	return tracing.GetIP(eth)
 }
 
+// ErrorField returns a field which records the error type, message, every
+// wrapped cause and the stack trace carried by the error.
+func ErrorField(err error) zap.Field {
+	return tracing.ErrorField(err)
+}
+
+// AuditError is returned by VerifyAudit for the first entry which breaks the
+// chain.
+type AuditError = tracing.AuditError
+
+// VerifyAudit verifies the chain of the entries of an audit file with the
+// HMAC key, it returns the number of verified entries.
+func VerifyAudit(filename string, key []byte) (int, error) {
+	return tracing.VerifyAuditFile(filename, key)
+}
+
+// SinkStatus is the status of a file sink, see tracing.SinkStatus.
+type SinkStatus = tracing.SinkStatus
+
+// FileSinkStatuses returns the status of the file sinks of all the loggers.
+func FileSinkStatuses() []SinkStatus {
+	return tracing.FileSinkStatuses()
+}
+
+// QueueStatus is the status of the queue of the hooks or the OTLP exporter
+// of a logger, see tracing.QueueStatus.
+type QueueStatus = tracing.QueueStatus
+
+// Status is the status of the file sinks and the queues of all the loggers.
+type Status = tracing.Status
+
+// GetStatus returns the status of the file sinks and the queues of all the
+// loggers.
+func GetStatus() Status {
+	return tracing.GetStatus()
+}
+
+// StatusHandler serves GetStatus as JSON, with the status code 503 if a file
+// sink is degraded.
+func StatusHandler() http.Handler {
+	return tracing.StatusHandler()
+}
+
 func NewTraceCtx(traceID string) context.Context {
 	return tracing.NewTraceCtx(traceID)
 }
@@ -38,6 +83,40 @@ func GetTraceID(ctx context.Context) string {
 	return tracing.GetTraceID(ctx)
 }
 
+// WithFields returns a context carrying the key value pairs, every *Context
+// log call with the context includes them.
+func WithFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	return tracing.WithFields(ctx, keysAndValues...)
+}
+
+// InjectBaggage adds the selected context fields to the W3C baggage header,
+// all the context fields are added if no keys are given.
+func InjectBaggage(ctx context.Context, header http.Header, keys ...string) {
+	tracing.InjectBaggage(ctx, header, keys...)
+}
+
+// ExtractBaggage returns a context carrying the selected members of the W3C
+// baggage header as fields, all the members are carried if no keys are given.
+func ExtractBaggage(ctx context.Context, header http.Header, keys ...string) context.Context {
+	return tracing.ExtractBaggage(ctx, header, keys...)
+}
+
+// BaggageTransport is a http.RoundTripper which adds the selected context
+// fields of the request to the W3C baggage header.
+type BaggageTransport = tracing.BaggageTransport
+
+// StartSpan starts a span on the global logger, the span logs a span-summary
+// entry with the duration and status when it ends.
+func StartSpan(ctx context.Context, name string) (context.Context, *tracing.Span) {
+	return GetLogger().StartSpan(ctx, name)
+}
+
+// StartSpan starts a span, the span logs a span-summary entry with the
+// duration and status when it ends.
+func (l *VLogger) StartSpan(ctx context.Context, name string) (context.Context, *tracing.Span) {
+	return l.log.StartSpan(ctx, name)
+}
+
 // Print logs a message at level Debug on the VLogger.
 func (l VLogger) Print(args ...interface{}) {
 	l.log.Print(oldCtx, fmt.Sprint(args...))
@@ -164,6 +243,31 @@ func (l VLogger) PanicfContext(ctx context.Context, format string, args ...inter
 	l.log.Panicf(ctx, format, args...)
 }
 
+// Err logs a message at level Error on the VLogger, with the error's type,
+// message, wrapped causes and stack trace.
+func (l VLogger) Err(err error, msg string, fields ...zap.Field) {
+	l.log.Err(oldCtx, err, msg, fields...)
+}
+
+func (l VLogger) ErrContext(ctx context.Context, err error, msg string, fields ...zap.Field) {
+	l.log.Err(ctx, err, msg, fields...)
+}
+
+// ErrAt logs a message at the specified level on the VLogger, with the error's
+// type, message, wrapped causes and stack trace.
+func (l VLogger) ErrAt(level string, err error, msg string, fields ...zap.Field) {
+	l.log.ErrAt(oldCtx, level, err, msg, fields...)
+}
+
+func (l VLogger) ErrAtContext(ctx context.Context, level string, err error, msg string, fields ...zap.Field) {
+	l.log.ErrAt(ctx, level, err, msg, fields...)
+}
+
+// Audit appends an entry to the audit file, see tracing.VLogger.Audit.
+func (l VLogger) Audit(ctx context.Context, actor, action, resource, outcome string, fields ...zap.Field) error {
+	return l.log.Audit(ctx, actor, action, resource, outcome, fields...)
+}
+
 // With return a logger with an extra field.
 func (l *VLogger) With(key string, value interface{}) *VLogger {
 	return &VLogger{log: l.log.With(key, value)}
@@ -183,3 +287,41 @@ func (l *VLogger) WithField(fields ...zap.Field) *VLogger {
 func (l *VLogger) AddCallerSkip(skip int) *VLogger {
 	return &VLogger{log: l.log.AddCallerSkip(skip)}
 }
+
+// StdLogger returns a *log.Logger which writes to the logger at the specified
+// level, lines with a level prefix such as "[ERROR]" are logged at that level
+// instead.
+func (l *VLogger) StdLogger(level string, fields ...zap.Field) *log.Logger {
+	return tracing.NewStdLogger(l.log, level, fields...)
+}
+
+// Named return a logger with name appended to the logger name.
+func (l *VLogger) Named(name string) *VLogger {
+	return &VLogger{log: l.log.Named(name)}
+}
+
+// Enabled returns true if the logger logs messages at level.
+func (l *VLogger) Enabled(level string) bool {
+	return l.log.Enabled(level)
+}
+
+// Sync flushes any buffered log entries.
+func (l *VLogger) Sync() error {
+	return l.log.Sync()
+}
+
+// Close flushes the entries and closes the files and connections of the
+// sinks, see tracing.VLogger.Close.
+func (l *VLogger) Close() error {
+	return l.log.Close()
+}
+
+// HandlePanic recovers a panic of the goroutine, logs it at panic level,
+// writes the crash report and panics again. Use it with defer:
+//
+//	defer logger.HandlePanic(ctx)
+func (l *VLogger) HandlePanic(ctx context.Context) {
+	if v := recover(); v != nil {
+		l.log.Repanic(ctx, v)
+	}
+}
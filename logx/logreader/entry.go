@@ -0,0 +1,318 @@
+// Package logreader reads the JSON log files of logx, with the T/L/M/LFILE,
+// LAPP and TRACE_ID fields, including the rotated and compressed files.
+package logreader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the keys of the entry, see tracing.Config.TimeKey.
+type Schema struct {
+	TimeKey    string
+	LevelKey   string
+	MessageKey string
+	CallerKey  string
+}
+
+// DefaultSchema is the default keys of logx.
+var DefaultSchema = Schema{
+	TimeKey:    "T",
+	LevelKey:   "L",
+	MessageKey: "M",
+	CallerKey:  "LFILE",
+}
+
+// schemaNames are the names of the keys of a Schema in its flag value.
+var schemaNames = []string{"time", "level", "message", "caller"}
+
+func (s *Schema) keys() []*string {
+	return []*string{&s.TimeKey, &s.LevelKey, &s.MessageKey, &s.CallerKey}
+}
+
+// String returns the keys of the schema as name=key pairs joined by commas,
+// e.g. time=T,level=L,message=M,caller=LFILE.
+func (s *Schema) String() string {
+	var pairs []string
+	for i, key := range s.keys() {
+		if *key != "" {
+			pairs = append(pairs, schemaNames[i]+"="+*key)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set sets the keys of the name=key pairs joined by commas, the names are
+// time, level, message and caller, e.g. time=ts,message=msg. It implements
+// flag.Value, the keys which are not in s are unchanged.
+func (s *Schema) Set(v string) error {
+	keys := s.keys()
+	for _, pair := range strings.Split(v, ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		i := indexOf(schemaNames, name)
+		if !ok || i < 0 || key == "" {
+			return errors.New("invalid schema " + strconv.Quote(pair) + ", want name=key of " + strings.Join(schemaNames, ", "))
+		}
+		*keys[i] = key
+	}
+	return nil
+}
+
+// withDefaults returns the schema with the keys of DefaultSchema for its
+// empty keys.
+func (s Schema) withDefaults() Schema {
+	def := DefaultSchema
+	for i, key := range s.keys() {
+		if *key == "" {
+			*key = *def.keys()[i]
+		}
+	}
+	return s
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Entry is a log entry.
+type Entry struct {
+	Time         time.Time
+	Level        string
+	Message      string
+	Caller       string
+	App          string
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+
+	// RequestID is the X-Request-ID of an entry of a span whose trace ID
+	// replaced it
+	RequestID string
+
+	// Fields are all the fields of the entry, including the ones above
+	Fields map[string]interface{}
+
+	// Raw is the JSON line of the entry
+	Raw []byte
+
+	// File is the file the entry is read from
+	File string
+}
+
+// ErrNotJSON is returned by Parse for a line which is not a JSON object.
+var ErrNotJSON = errors.New("not a json entry")
+
+// Parse parses a JSON line of the schema.
+func (s Schema) Parse(line []byte) (*Entry, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return nil, ErrNotJSON
+	}
+
+	fields := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	e := &Entry{
+		Level:        strings.ToUpper(stringField(fields, s.LevelKey)),
+		Message:      stringField(fields, s.MessageKey),
+		Caller:       stringField(fields, s.CallerKey),
+		App:          stringField(fields, "LAPP"),
+		TraceID:      stringField(fields, "TRACE_ID"),
+		SpanID:       stringField(fields, "SPAN_ID"),
+		ParentSpanID: stringField(fields, "PARENT_SPAN_ID"),
+		RequestID:    stringField(fields, "REQUEST_ID"),
+		Fields:       fields,
+		Raw:          append([]byte(nil), line...),
+	}
+	e.Time, _ = ParseTime(fields[s.TimeKey])
+	return e, nil
+}
+
+// InTrace reports whether the entry has the trace ID, as its TRACE_ID or
+// its REQUEST_ID.
+func (e *Entry) InTrace(traceID string) bool {
+	return e.TraceID == traceID || e.RequestID == traceID
+}
+
+// Parse parses a JSON line of the default schema.
+func Parse(line []byte) (*Entry, error) {
+	return DefaultSchema.Parse(line)
+}
+
+func stringField(fields map[string]interface{}, key string) string {
+	switch v := fields[key].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return toString(v)
+	}
+}
+
+var timeLayouts = []string{
+	"2006-01-02T15:04:05.000Z0700",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+}
+
+// ParseTime parses the time of an entry in the time formats of logx, an
+// epoch time is in seconds, milliseconds or nanoseconds by its magnitude.
+func ParseTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, errors.New("invalid time: " + v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return epochTime(f), nil
+	case float64:
+		return epochTime(v), nil
+	default:
+		return time.Time{}, errors.New("no time")
+	}
+}
+
+func epochTime(f float64) time.Time {
+	switch {
+	case f > 1e17:
+		return time.Unix(0, int64(f))
+	case f > 1e11:
+		return time.UnixMilli(int64(f))
+	default:
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9))
+	}
+}
+
+// Field returns the value of a field, the key of a nested field is joined by
+// dots, e.g. httpRequest.status.
+func (e *Entry) Field(key string) (interface{}, bool) {
+	if v, ok := e.Fields[key]; ok {
+		return v, true
+	}
+	var cur interface{} = e.Fields
+	for _, k := range strings.Split(key, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// FieldString returns the value of a field as a string, "" if not found.
+func (e *Entry) FieldString(key string) string {
+	v, ok := e.Field(key)
+	if !ok {
+		return ""
+	}
+	return toString(v)
+}
+
+// FieldFloat returns the value of a field as a number.
+func (e *Entry) FieldFloat(key string) (float64, bool) {
+	v, ok := e.Field(key)
+	if !ok {
+		return 0, false
+	}
+	return toFloat(v)
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+var levels = map[string]int{
+	"DEBUG":  -1,
+	"INFO":   0,
+	"WARN":   1,
+	"ERROR":  2,
+	"DPANIC": 3,
+	"PANIC":  4,
+	"FATAL":  5,
+}
+
+// LevelValue returns the order of a level name, unknown levels are info.
+func LevelValue(level string) int {
+	return levels[strings.ToUpper(level)]
+}
+
+// Latency returns the latency of the request of the entry, of the
+// httpRequest.latency or grpc.latency field. A duration in a number is in
+// nanoseconds, the default DurationEncoding.
+func (e *Entry) Latency() (time.Duration, bool) {
+	for _, key := range []string{"httpRequest.latency", "grpc.latency"} {
+		v, ok := e.Field(key)
+		if !ok {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			if d, err := time.ParseDuration(s); err == nil {
+				return d, true
+			}
+			continue
+		}
+		if f, ok := toFloat(v); ok {
+			return time.Duration(f), true
+		}
+	}
+	return 0, false
+}
+
+// Status returns the httpRequest.status of the entry, 0 if none.
+func (e *Entry) Status() int {
+	f, _ := e.FieldFloat("httpRequest.status")
+	return int(f)
+}
@@ -0,0 +1,73 @@
+package logreader
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+// Files returns the rotated backups of a log file from the oldest to the
+// newest, followed by the file itself if it exists. A directory is expanded
+// to the files of its logs.
+func Files(filename string) ([]string, error) {
+	fi, statErr := os.Stat(filename)
+	if statErr == nil && fi.IsDir() {
+		return dirFiles(filename)
+	}
+
+	files, err := tracing.BackupFiles(filename)
+	if err != nil {
+		return nil, err
+	}
+	if statErr == nil {
+		files = append(files, filename)
+	}
+	if len(files) == 0 {
+		return nil, statErr
+	}
+	return files, nil
+}
+
+func isLogName(name string) bool {
+	name = strings.TrimSuffix(name, ".gz")
+	return strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".json")
+}
+
+// dirFiles returns the files of the logs of a directory, the backups of a
+// log are followed by the log itself.
+func dirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	logs := map[string]bool{}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !isLogName(e.Name()) {
+			continue
+		}
+		if name := tracing.BackupOf(e.Name()); name != "" {
+			logs[name] = true
+		} else {
+			logs[e.Name()] = true
+		}
+	}
+
+	names := make([]string, 0, len(logs))
+	for name := range logs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []string
+	for _, name := range names {
+		fs, err := Files(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fs...)
+	}
+	return files, nil
+}
@@ -0,0 +1,214 @@
+package logreader
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter selects entries, the zero value matches all entries.
+type Filter struct {
+	// Since and Until are the time range [Since, Until) of the entries
+	Since time.Time
+	Until time.Time
+
+	// Level is the minimum level of the entries
+	Level string
+
+	// TraceID and App match the TRACE_ID or REQUEST_ID, and the LAPP of the
+	// entries
+	TraceID string
+	App     string
+
+	// Exprs are the field expressions all the entries match, see ParseExpr
+	Exprs Exprs
+}
+
+// Match reports whether e is selected by the filter.
+func (f *Filter) Match(e *Entry) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	if f.Level != "" && LevelValue(e.Level) < LevelValue(f.Level) {
+		return false
+	}
+	if f.TraceID != "" && !e.InTrace(f.TraceID) {
+		return false
+	}
+	if f.App != "" && e.App != f.App {
+		return false
+	}
+	for _, x := range f.Exprs {
+		if !x.Match(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidLevel reports whether level is a level name of zap.
+func ValidLevel(level string) bool {
+	_, ok := levels[strings.ToUpper(level)]
+	return ok
+}
+
+// Expr is a field expression.
+type Expr struct {
+	Key   string
+	Op    string
+	Value string
+
+	re  *regexp.Regexp
+	num float64
+}
+
+var exprOps = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+// ParseExpr parses a field expression, the key of a nested field is joined
+// by dots:
+//
+//	key          the field exists
+//	!key         the field does not exist
+//	key=value    the field equals value
+//	key!=value   the field does not equal value
+//	key~regexp   the field matches regexp
+//	key!~regexp  the field does not match regexp
+//	key>number   also >=, < and <=, the field is a number compared to number
+func ParseExpr(s string) (Expr, error) {
+	i := strings.IndexAny(s, "=!~<>")
+	switch {
+	case i < 0:
+		return Expr{Key: s, Op: "exists"}, validKey(s)
+	case i == 0 && s[0] == '!' && strings.IndexAny(s[1:], "=!~<>") < 0:
+		return Expr{Key: s[1:], Op: "!exists"}, validKey(s[1:])
+	}
+
+	x := Expr{Key: s[:i]}
+	if err := validKey(x.Key); err != nil {
+		return x, err
+	}
+	for _, op := range exprOps {
+		if strings.HasPrefix(s[i:], op) {
+			x.Op, x.Value = op, s[i+len(op):]
+			break
+		}
+	}
+
+	var err error
+	switch x.Op {
+	case "":
+		return x, fmt.Errorf("invalid expression %q", s)
+	case "~", "!~":
+		if x.re, err = regexp.Compile(x.Value); err != nil {
+			return x, fmt.Errorf("invalid expression %q: %v", s, err)
+		}
+	case ">", ">=", "<", "<=":
+		if x.num, err = strconv.ParseFloat(x.Value, 64); err != nil {
+			return x, fmt.Errorf("invalid expression %q: %s is not a number", s, x.Value)
+		}
+	}
+	return x, nil
+}
+
+func validKey(key string) error {
+	if key == "" {
+		return errors.New("empty key in expression")
+	}
+	return nil
+}
+
+// Match reports whether the field of e matches the expression.
+func (x Expr) Match(e *Entry) bool {
+	v, ok := e.Field(x.Key)
+	switch x.Op {
+	case "exists":
+		return ok
+	case "!exists":
+		return !ok
+	case "=":
+		return ok && toString(v) == x.Value
+	case "!=":
+		return !ok || toString(v) != x.Value
+	case "~":
+		return ok && x.re.MatchString(toString(v))
+	case "!~":
+		return !ok || !x.re.MatchString(toString(v))
+	}
+
+	f, ok := toFloat(v)
+	if !ok {
+		return false
+	}
+	switch x.Op {
+	case ">":
+		return f > x.num
+	case ">=":
+		return f >= x.num
+	case "<":
+		return f < x.num
+	case "<=":
+		return f <= x.num
+	}
+	return false
+}
+
+// Exprs are field expressions, which are a flag.Value of a repeated flag.
+type Exprs []Expr
+
+// String returns the expressions separated by commas.
+func (xs *Exprs) String() string {
+	s := make([]string, len(*xs))
+	for i, x := range *xs {
+		s[i] = x.String()
+	}
+	return strings.Join(s, ",")
+}
+
+// Set parses and appends an expression.
+func (xs *Exprs) Set(s string) error {
+	x, err := ParseExpr(s)
+	if err != nil {
+		return err
+	}
+	*xs = append(*xs, x)
+	return nil
+}
+
+// String returns the expression in the syntax of ParseExpr.
+func (x Expr) String() string {
+	switch x.Op {
+	case "exists":
+		return x.Key
+	case "!exists":
+		return "!" + x.Key
+	}
+	return x.Key + x.Op + x.Value
+}
+
+var timeArgLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTimeArg parses a time of a command line in RFC 3339 or a date and
+// time in the local zone, or a duration before now, e.g. 1h30m.
+func ParseTimeArg(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeArgLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, want RFC 3339, 2006-01-02 15:04:05 or a duration", s)
+}
@@ -0,0 +1,235 @@
+package logreader
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+// pollInterval is the interval to check a followed file for new entries.
+var pollInterval = 200 * time.Millisecond
+
+// Options are the options of a Reader.
+type Options struct {
+	// Schema is the keys of the entries, see tracing.Config.TimeKey
+	// default is DefaultSchema, also for its empty keys
+	Schema Schema
+
+	// Key is the AES key of encrypted files, see tracing.NewDecryptReader
+	// default is nil, the files are not encrypted
+	Key []byte
+}
+
+func (o Options) schema() Schema {
+	return o.Schema.withDefaults()
+}
+
+// Reader reads the entries of log files in order. Lines which are not JSON
+// entries, e.g. of the console encoding, are skipped.
+type Reader struct {
+	opts  Options
+	files []string
+
+	// ctx and follow are set by Follow
+	ctx    context.Context
+	follow string
+	seeked bool
+
+	file string
+	f    *os.File
+	r    *bufio.Reader
+}
+
+// NewReader returns a reader of the entries of files, which are plain,
+// compressed with gzip or encrypted with opts.Key. Use Files to get the
+// rotated backups of a log file.
+func NewReader(files []string, opts Options) *Reader {
+	return &Reader{opts: opts, files: files}
+}
+
+// Follow returns a reader of the entries appended to filename like tail -f,
+// starting at the end of the file. The file is reopened when it is rotated
+// or truncated, and Next blocks for new entries until ctx is done.
+func Follow(ctx context.Context, filename string, opts Options) *Reader {
+	return &Reader{opts: opts, ctx: ctx, follow: filename}
+}
+
+// Next returns the next entry, io.EOF at the end of the files or the error
+// of the context of Follow.
+func (r *Reader) Next() (*Entry, error) {
+	schema := r.opts.schema()
+	for {
+		if r.r == nil {
+			if err := r.open(); err != nil {
+				return nil, err
+			}
+		}
+
+		line, err := r.r.ReadBytes('\n')
+		if len(line) > 0 {
+			if e, perr := schema.Parse(line); perr == nil {
+				e.File = r.file
+				return e, nil
+			}
+		}
+		if err == io.EOF {
+			r.closeFile()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Close closes the current file of the reader.
+func (r *Reader) Close() error {
+	r.files = nil
+	return r.closeFile()
+}
+
+func (r *Reader) closeFile() error {
+	r.r = nil
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+func (r *Reader) open() error {
+	if r.ctx != nil {
+		return r.openFollow()
+	}
+	if len(r.files) == 0 {
+		return io.EOF
+	}
+	r.file, r.files = r.files[0], r.files[1:]
+
+	f, err := os.Open(r.file)
+	if err != nil {
+		return err
+	}
+	rd, err := r.decode(f, true)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.r = f, bufio.NewReader(rd)
+	return nil
+}
+
+// openFollow opens the followed file, waiting for it to be created.
+func (r *Reader) openFollow() error {
+	var f *os.File
+	for {
+		var err error
+		if f, err = os.Open(r.follow); err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if err := wait(r.ctx); err != nil {
+			return err
+		}
+	}
+
+	t := &tailReader{ctx: r.ctx, f: f, name: r.follow}
+	if !r.seeked {
+		// only the first file starts at the end, a rotated one is new
+		off, err := f.Seek(0, io.SeekEnd)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		t.off = off
+		r.seeked = true
+	}
+	rd, err := r.decode(t, false)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.f, r.r = r.follow, f, bufio.NewReader(rd)
+	return nil
+}
+
+// decode returns the plaintext of a file, gzip is detected by its magic.
+func (r *Reader) decode(rd io.Reader, gz bool) (io.Reader, error) {
+	if r.opts.Key != nil {
+		return tracing.NewDecryptReader(rd, r.opts.Key)
+	}
+	if !gz {
+		return rd, nil
+	}
+	br := bufio.NewReader(rd)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+func wait(ctx context.Context) error {
+	t := time.NewTimer(pollInterval)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// tailReader reads a growing file, blocking at its end. io.EOF is returned
+// only when the name refers to another file, or the file is truncated.
+type tailReader struct {
+	ctx  context.Context
+	f    *os.File
+	name string
+	off  int64
+}
+
+func (t *tailReader) Read(p []byte) (int, error) {
+	for {
+		n, err := t.f.Read(p)
+		t.off += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if t.rotated() {
+			// the last writes before the rotation
+			n, _ := t.f.Read(p)
+			t.off += int64(n)
+			if n > 0 {
+				return n, nil
+			}
+			return 0, io.EOF
+		}
+		if err := wait(t.ctx); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (t *tailReader) rotated() bool {
+	fi, err := os.Stat(t.name)
+	if err != nil {
+		// removed, wait for the new file
+		return false
+	}
+	cur, err := t.f.Stat()
+	if err != nil {
+		return true
+	}
+	return !os.SameFile(fi, cur) || fi.Size() < t.off
+}
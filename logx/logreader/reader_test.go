@@ -0,0 +1,286 @@
+package logreader_test
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/logreader"
+	"github.com/kakabei/kfgolib/logx/tracing"
+	"go.uber.org/zap"
+)
+
+func writeLog(t *testing.T, filename, app string, n int) {
+	t.Helper()
+	logger := tracing.NewLogger(tracing.Config{
+		EnableFile:    true,
+		Filename:      filename,
+		EnableCaller:  true,
+		FileLevel:     "debug",
+		FileEncodeing: "json",
+		AppName:       app,
+	})
+	for i := 0; i < n; i++ {
+		ctx := tracing.NewTraceCtx(fmt.Sprintf("t%d", i%2))
+		l := logger.WithField(zap.Int("i", i),
+			zap.Any("httpRequest", map[string]interface{}{"status": 200 + 100*i, "requestUrl": "/orders"}))
+		if i%2 == 0 {
+			l.Info(ctx, "request")
+		} else {
+			l.Error(ctx, "request failed")
+		}
+	}
+	logger.Sync()
+}
+
+func gzipFile(t *testing.T, src, dst string) {
+	t.Helper()
+	b, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	zw.Write(b)
+	zw.Close()
+	f.Close()
+	os.Remove(src)
+}
+
+func readAll(t *testing.T, r *logreader.Reader, f logreader.Filter) []*logreader.Entry {
+	t.Helper()
+	defer r.Close()
+	var entries []*logreader.Entry
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"app.log",
+		"app-2024-01-02T00-00-00.000.log.gz",
+		"app-2024-01-01T00-00-00.000.log",
+		"app-old.log",
+		"other-2024-01-01T00-00-00.000.log.gz",
+		"notes.txt",
+	} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0600)
+	}
+
+	files, err := logreader.Files(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "app-2024-01-01T00-00-00.000.log"),
+		filepath.Join(dir, "app-2024-01-02T00-00-00.000.log.gz"),
+		filepath.Join(dir, "app.log"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Files = %v, want %v", files, want)
+	}
+
+	// the backups of a removed log, and the logs of a directory
+	if files, err := logreader.Files(filepath.Join(dir, "other.log")); err != nil || len(files) != 1 {
+		t.Errorf("Files = %v, %v", files, err)
+	}
+	files, err = logreader.Files(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = append([]string{filepath.Join(dir, "app-old.log")}, want...)
+	want = append(want, filepath.Join(dir, "other-2024-01-01T00-00-00.000.log.gz"))
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Files = %v, want %v", files, want)
+	}
+
+	if _, err := logreader.Files(filepath.Join(dir, "missing.log")); !os.IsNotExist(err) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestReader(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	writeLog(t, filename, "old", 2)
+	gzipFile(t, filename, filepath.Join(dir, "app-2024-01-01T00-00-00.000.log.gz"))
+	writeLog(t, filename, "new", 4)
+	// a console line is skipped
+	f, _ := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("2024-01-01 00:00:00\tINFO\tconsole line\n")
+	f.Close()
+
+	files, err := logreader.Files(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := readAll(t, logreader.NewReader(files, logreader.Options{}), logreader.Filter{})
+	if len(entries) != 6 {
+		t.Fatalf("read %d entries, want 6", len(entries))
+	}
+	e := entries[0]
+	if e.App != "old" || e.Level != "INFO" || e.Message != "request" || e.TraceID != "t0" ||
+		e.Caller == "" || e.Time.IsZero() || e.FieldString("httpRequest.status") != "200" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if entries[5].App != "new" || entries[5].FieldString("i") != "3" {
+		t.Errorf("unexpected last entry %s", entries[5].Raw)
+	}
+
+	for _, c := range []struct {
+		filter logreader.Filter
+		want   int
+	}{
+		{logreader.Filter{App: "new"}, 4},
+		{logreader.Filter{TraceID: "t1"}, 3},
+		{logreader.Filter{Level: "warn"}, 3},
+		{logreader.Filter{App: "new", Level: "error", TraceID: "t1"}, 2},
+		{logreader.Filter{Since: time.Now().Add(-time.Hour)}, 6},
+		{logreader.Filter{Until: time.Now().Add(-time.Hour)}, 0},
+		{logreader.Filter{Exprs: exprs(t, "httpRequest.status>=400")}, 2},
+		{logreader.Filter{Exprs: exprs(t, "httpRequest.status=300", "LAPP=new")}, 1},
+		{logreader.Filter{Exprs: exprs(t, "M~fail", "i!=1")}, 1},
+		{logreader.Filter{Exprs: exprs(t, "missing")}, 0},
+		{logreader.Filter{Exprs: exprs(t, "!missing")}, 6},
+	} {
+		entries := readAll(t, logreader.NewReader(files, logreader.Options{}), c.filter)
+		if len(entries) != c.want {
+			t.Errorf("%+v matched %d entries, want %d", c.filter, len(entries), c.want)
+		}
+	}
+}
+
+func exprs(t *testing.T, s ...string) []logreader.Expr {
+	t.Helper()
+	var xs []logreader.Expr
+	for _, s := range s {
+		x, err := logreader.ParseExpr(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		xs = append(xs, x)
+	}
+	return xs
+}
+
+func TestParseExpr(t *testing.T) {
+	for _, s := range []string{"", "=1", "a>b", "a~(", "a!b", "!"} {
+		if _, err := logreader.ParseExpr(s); err == nil {
+			t.Errorf("ParseExpr(%q) is valid", s)
+		}
+	}
+	for _, s := range []string{"a", "!a", "a=1", "a!=1", "a~^x", "a!~x", "a>1", "a>=1.5", "a<1", "a<=-1", "a=b=c"} {
+		x, err := logreader.ParseExpr(s)
+		if err != nil || x.String() != s {
+			t.Errorf("ParseExpr(%q) = %v, %v", s, x, err)
+		}
+	}
+}
+
+func TestReaderSchema(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	logger := tracing.NewLogger(tracing.Config{
+		EnableFile:    true,
+		Filename:      filename,
+		FileLevel:     "debug",
+		FileEncodeing: "json",
+		TimeKey:       "ts",
+		MessageKey:    "msg",
+	})
+	logger.Warn(tracing.NewTraceCtx("t1"), "custom keys")
+	logger.Sync()
+
+	var schema logreader.Schema
+	if err := schema.Set("time=ts, message=msg"); err != nil {
+		t.Fatal(err)
+	}
+	if s := schema.String(); s != "time=ts,message=msg" {
+		t.Errorf("unexpected schema %s", s)
+	}
+	for _, v := range []string{"", "time", "time=", "date=ts"} {
+		if err := new(logreader.Schema).Set(v); err == nil {
+			t.Errorf("Set(%q) is valid", v)
+		}
+	}
+
+	// the level key is the default
+	entries := readAll(t, logreader.NewReader([]string{filename}, logreader.Options{Schema: schema}), logreader.Filter{})
+	if len(entries) != 1 || entries[0].Message != "custom keys" || entries[0].Level != "WARN" || entries[0].Time.IsZero() {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	appendLine(t, filename, "before")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	r := logreader.Follow(ctx, filename, logreader.Options{})
+	defer r.Close()
+
+	entries := make(chan *logreader.Entry)
+	go func() {
+		for {
+			e, err := r.Next()
+			if err != nil {
+				close(entries)
+				return
+			}
+			entries <- e
+		}
+	}()
+
+	// wait for the file to be opened at its end
+	time.Sleep(300 * time.Millisecond)
+	appendLine(t, filename, "first")
+	if e := <-entries; e == nil || e.Message != "first" {
+		t.Fatalf("unexpected entry %v", e)
+	}
+
+	// rotated, the lines written before the rotation are read first
+	appendLine(t, filename, "second")
+	os.Rename(filename, filepath.Join(dir, "app-2024-01-01T00-00-00.000.log"))
+	appendLine(t, filename, "third")
+	for _, want := range []string{"second", "third"} {
+		if e := <-entries; e == nil || e.Message != want {
+			t.Fatalf("unexpected entry %v, want %s", e, want)
+		}
+	}
+
+	cancel()
+	if _, ok := <-entries; ok {
+		t.Error("Next is not stopped by the context")
+	}
+}
+
+func appendLine(t *testing.T, filename, msg string) {
+	t.Helper()
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fmt.Fprintf(f, `{"L":"INFO","T":"%s","M":"%s","LAPP":"app"}`+"\n", time.Now().Format("2006-01-02T15:04:05.000Z0700"), msg)
+}
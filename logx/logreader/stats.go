@@ -0,0 +1,245 @@
+package logreader
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	uuidPattern   = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	ipPattern     = regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`)
+	idPattern     = regexp.MustCompile(`[0-9A-Za-z_]*\d[0-9A-Za-z_]*`)
+	numberPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+	quotedPattern = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+)
+
+// minIDLength is the minimum length of a token of letters and digits which
+// is an ID, e.g. 4bf92f3577b34da6.
+const minIDLength = 8
+
+// Template returns the template of a message, the quoted strings, UUIDs, IP
+// addresses, IDs and numbers are replaced by placeholders, e.g.
+//
+//	order 12 of user "alice" failed after 3.5s: 4bf92f3577b34da6
+//
+// is "order <num> of user <str> failed after <num>s: <id>".
+func Template(msg string) string {
+	msg = quotedPattern.ReplaceAllString(msg, "<str>")
+	msg = uuidPattern.ReplaceAllString(msg, "<uuid>")
+	msg = ipPattern.ReplaceAllString(msg, "<ip>")
+	msg = idPattern.ReplaceAllStringFunc(msg, func(s string) string {
+		if len(s) >= minIDLength && strings.IndexFunc(s, isLetter) >= 0 {
+			return "<id>"
+		}
+		return s
+	})
+	return numberPattern.ReplaceAllString(msg, "<num>")
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// isError reports whether an entry is at error level or above, or of a
+// request with a 5xx status.
+func isError(e *Entry) bool {
+	return LevelValue(e.Level) >= levels["ERROR"] || e.Status() >= 500
+}
+
+// TemplateCount is the count of the entries of a message template.
+type TemplateCount struct {
+	Template string    `json:"template"`
+	Example  string    `json:"example"`
+	Level    string    `json:"level"`
+	Count    int       `json:"count"`
+	Errors   int       `json:"errors"`
+	First    time.Time `json:"first"`
+	Last     time.Time `json:"last"`
+}
+
+// Bucket is the count of the entries in a time bucket.
+type Bucket struct {
+	Start   time.Time `json:"start"`
+	Entries int       `json:"entries"`
+	Errors  int       `json:"errors"`
+}
+
+// ErrorRate returns the ratio of the errors to the entries of the bucket.
+func (b Bucket) ErrorRate() float64 {
+	if b.Entries == 0 {
+		return 0
+	}
+	return float64(b.Errors) / float64(b.Entries)
+}
+
+// Stats are the statistics of entries.
+type Stats struct {
+	Start   time.Time
+	End     time.Time
+	Entries int
+	Errors  int
+
+	// Templates are the counts of the message templates, see Template
+	Templates map[string]*TemplateCount
+
+	// Statuses and RetCodes are the counts of httpRequest.status and
+	// httpRequest.retCode
+	Statuses map[int]int
+	RetCodes map[int]int
+
+	// Slowest are the entries of the slowest requests by latency, see
+	// Entry.Latency
+	Slowest []*Entry
+
+	bucket  time.Duration
+	buckets map[time.Time]*Bucket
+	slowest int
+}
+
+// NewStats returns the statistics of entries with the error rates over
+// buckets of the duration, and at most slowest slowest requests.
+func NewStats(bucket time.Duration, slowest int) *Stats {
+	if bucket <= 0 {
+		bucket = time.Minute
+	}
+	return &Stats{
+		Templates: map[string]*TemplateCount{},
+		Statuses:  map[int]int{},
+		RetCodes:  map[int]int{},
+		bucket:    bucket,
+		buckets:   map[time.Time]*Bucket{},
+		slowest:   slowest,
+	}
+}
+
+// Add adds an entry to the statistics.
+func (s *Stats) Add(e *Entry) {
+	s.Entries++
+	failed := isError(e)
+	if failed {
+		s.Errors++
+	}
+	if !e.Time.IsZero() {
+		if s.Start.IsZero() || e.Time.Before(s.Start) {
+			s.Start = e.Time
+		}
+		if e.Time.After(s.End) {
+			s.End = e.Time
+		}
+		start := e.Time.Truncate(s.bucket)
+		b := s.buckets[start]
+		if b == nil {
+			b = &Bucket{Start: start}
+			s.buckets[start] = b
+		}
+		b.Entries++
+		if failed {
+			b.Errors++
+		}
+	}
+
+	tmpl := Template(e.Message)
+	tc := s.Templates[tmpl]
+	if tc == nil {
+		tc = &TemplateCount{Template: tmpl, Example: e.Message, Level: e.Level, First: e.Time}
+		s.Templates[tmpl] = tc
+	}
+	tc.Count++
+	if failed {
+		tc.Errors++
+	}
+	if LevelValue(e.Level) > LevelValue(tc.Level) {
+		tc.Level = e.Level
+	}
+	if e.Time.Before(tc.First) {
+		tc.First = e.Time
+	}
+	if e.Time.After(tc.Last) {
+		tc.Last = e.Time
+	}
+
+	if status := e.Status(); status != 0 {
+		s.Statuses[status]++
+	}
+	if code, ok := e.FieldFloat("httpRequest.retCode"); ok {
+		s.RetCodes[int(code)]++
+	}
+	if latency, ok := e.Latency(); ok && s.slowest > 0 {
+		s.addSlow(e, latency)
+	}
+}
+
+func (s *Stats) addSlow(e *Entry, latency time.Duration) {
+	i := sort.Search(len(s.Slowest), func(i int) bool {
+		d, _ := s.Slowest[i].Latency()
+		return d < latency
+	})
+	if i >= s.slowest {
+		return
+	}
+	s.Slowest = append(s.Slowest, nil)
+	copy(s.Slowest[i+1:], s.Slowest[i:])
+	s.Slowest[i] = e
+	if len(s.Slowest) > s.slowest {
+		s.Slowest = s.Slowest[:s.slowest]
+	}
+}
+
+// TopTemplates returns the n templates with the most entries, all of them
+// if n is 0.
+func (s *Stats) TopTemplates(n int) []*TemplateCount {
+	return topTemplates(s.Templates, n, func(tc *TemplateCount) int { return tc.Count })
+}
+
+// Buckets returns the buckets from the first to the last entry, including
+// the empty ones.
+func (s *Stats) Buckets() []Bucket {
+	if len(s.buckets) == 0 {
+		return nil
+	}
+	var buckets []Bucket
+	end := s.End.Truncate(s.bucket)
+	for t := s.Start.Truncate(s.bucket); !t.After(end); t = t.Add(s.bucket) {
+		if b := s.buckets[t]; b != nil {
+			buckets = append(buckets, *b)
+		} else {
+			buckets = append(buckets, Bucket{Start: t})
+		}
+	}
+	return buckets
+}
+
+// NewErrorTemplates returns the templates with errors which have no errors
+// in the baseline, e.g. the entries before a deploy, by the most errors.
+func (s *Stats) NewErrorTemplates(baseline *Stats) []*TemplateCount {
+	templates := map[string]*TemplateCount{}
+	for tmpl, tc := range s.Templates {
+		if tc.Errors == 0 {
+			continue
+		}
+		if base := baseline.Templates[tmpl]; base != nil && base.Errors > 0 {
+			continue
+		}
+		templates[tmpl] = tc
+	}
+	return topTemplates(templates, 0, func(tc *TemplateCount) int { return tc.Errors })
+}
+
+func topTemplates(templates map[string]*TemplateCount, n int, count func(*TemplateCount) int) []*TemplateCount {
+	top := make([]*TemplateCount, 0, len(templates))
+	for _, tc := range templates {
+		top = append(top, tc)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if ci, cj := count(top[i]), count(top[j]); ci != cj {
+			return ci > cj
+		}
+		return top[i].Template < top[j].Template
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
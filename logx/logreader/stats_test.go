@@ -0,0 +1,91 @@
+package logreader_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/logreader"
+)
+
+func TestTemplate(t *testing.T) {
+	for msg, want := range map[string]string{
+		`order 12 of user "alice" failed after 3.5s: 4bf92f3577b34da6`: "order <num> of user <str> failed after <num>s: <id>",
+		"request 0b8e9a34-4f2c-4d4e-9b1a-0c2d3e4f5a6b done":            "request <uuid> done",
+		"dial tcp 10.0.0.12:5432: connection refused":                  "dial tcp <ip>: connection refused",
+		"order-12345678 paid by user42":                                "order-<num> paid by user<num>",
+		"service started":                                              "service started",
+	} {
+		if got := logreader.Template(msg); got != want {
+			t.Errorf("Template(%q) = %q, want %q", msg, got, want)
+		}
+	}
+}
+
+func statsEntry(t *testing.T, sec int, level, msg string, status int, latency string) *logreader.Entry {
+	t.Helper()
+	line := fmt.Sprintf(`{"L":%q,"T":"2024-05-01T10:%02d:%02d.000Z","M":%q,"LAPP":"order"`, level, sec/60, sec%60, msg)
+	if status != 0 {
+		line += fmt.Sprintf(`,"httpRequest":{"status":%d,"latency":%q,"retCode":%d}`, status, latency, status/100)
+	}
+	e, err := logreader.Parse([]byte(line + "}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestStats(t *testing.T) {
+	entries := []*logreader.Entry{
+		statsEntry(t, 0, "INFO", "order 1 created", 200, "30ms"),
+		statsEntry(t, 10, "INFO", "order 2 created", 200, "10ms"),
+		statsEntry(t, 20, "ERROR", "cache miss 7", 0, ""),
+		statsEntry(t, 130, "INFO", "order 3 created", 500, "2s"),
+		statsEntry(t, 140, "ERROR", "db timeout on 10.0.0.1:5432", 0, ""),
+		statsEntry(t, 150, "INFO", "order 4 created", 200, "500ms"),
+	}
+	s := logreader.NewStats(time.Minute, 2)
+	for _, e := range entries {
+		s.Add(e)
+	}
+
+	if s.Entries != 6 || s.Errors != 3 {
+		t.Errorf("Entries, Errors = %d, %d", s.Entries, s.Errors)
+	}
+	top := s.TopTemplates(1)
+	if len(top) != 1 || top[0].Template != "order <num> created" || top[0].Count != 4 || top[0].Errors != 1 ||
+		top[0].Example != "order 1 created" || top[0].Last.Sub(top[0].First) != 150*time.Second {
+		t.Errorf("unexpected top templates %+v", top[0])
+	}
+	if len(s.TopTemplates(0)) != 3 {
+		t.Errorf("unexpected templates %v", s.Templates)
+	}
+
+	var rates []string
+	for _, b := range s.Buckets() {
+		rates = append(rates, fmt.Sprintf("%d/%d", b.Errors, b.Entries))
+	}
+	if fmt.Sprint(rates) != "[1/3 0/0 2/3]" {
+		t.Errorf("unexpected buckets %v", rates)
+	}
+
+	if len(s.Slowest) != 2 || s.Slowest[0].Message != "order 3 created" || s.Slowest[1].Message != "order 4 created" {
+		t.Errorf("unexpected slowest %v", s.Slowest)
+	}
+	if s.Statuses[200] != 3 || s.Statuses[500] != 1 || s.RetCodes[2] != 3 || s.RetCodes[5] != 1 {
+		t.Errorf("unexpected statuses %v, retcodes %v", s.Statuses, s.RetCodes)
+	}
+
+	// the entries after a deploy
+	baseline, current := logreader.NewStats(time.Minute, 0), logreader.NewStats(time.Minute, 0)
+	for _, e := range entries[:3] {
+		baseline.Add(e)
+	}
+	for _, e := range append(entries[3:], statsEntry(t, 160, "ERROR", "cache miss 9", 0, "")) {
+		current.Add(e)
+	}
+	added := current.NewErrorTemplates(baseline)
+	if len(added) != 2 || added[0].Template != "db timeout on <ip>" || added[1].Template != "order <num> created" {
+		t.Errorf("unexpected new error templates %+v", added)
+	}
+}
@@ -0,0 +1,111 @@
+package logreader
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+// Timeline is the entries of a trace from several services in time order,
+// each service is a lane of the timeline.
+type Timeline struct {
+	TraceID  string
+	Start    time.Time
+	End      time.Time
+	Services []string
+	Events   []Event
+	Errors   int
+}
+
+// Event is an entry of a timeline.
+type Event struct {
+	*Entry
+
+	// Service is the LAPP of the entry, or the name of its file
+	Service string
+
+	// Lane is the index of the service in Timeline.Services
+	Lane int
+
+	// Offset is the time since the start of the timeline, and Gap the time
+	// since the previous event. Both are zero for an entry without a time,
+	// which is placed after the others
+	Offset time.Duration
+	Gap    time.Duration
+
+	// Latency is the latency of the request of the entry, see Entry.Latency
+	Latency time.Duration
+
+	// Error is set for an entry at error level or above, or of a request with
+	// a 5xx status
+	Error bool
+}
+
+// NewTimeline returns the timeline of the entries of the trace, the entries
+// of other traces are ignored.
+func NewTimeline(traceID string, entries []*Entry) *Timeline {
+	t := &Timeline{TraceID: traceID}
+	var trace []*Entry
+	for _, e := range entries {
+		if e.InTrace(traceID) {
+			trace = append(trace, e)
+		}
+	}
+	// the entries of a file are in order, keep it for the same time
+	sort.SliceStable(trace, func(i, j int) bool {
+		if trace[i].Time.IsZero() || trace[j].Time.IsZero() {
+			return !trace[i].Time.IsZero() && trace[j].Time.IsZero()
+		}
+		return trace[i].Time.Before(trace[j].Time)
+	})
+	timed := sort.Search(len(trace), func(i int) bool { return trace[i].Time.IsZero() })
+	if timed > 0 {
+		t.Start, t.End = trace[0].Time, trace[timed-1].Time
+	}
+
+	lanes := map[string]int{}
+	for i, e := range trace {
+		ev := Event{Entry: e, Service: serviceName(e)}
+		lane, ok := lanes[ev.Service]
+		if !ok {
+			lane = len(t.Services)
+			lanes[ev.Service] = lane
+			t.Services = append(t.Services, ev.Service)
+		}
+		ev.Lane = lane
+		if i < timed {
+			ev.Offset = e.Time.Sub(t.Start)
+			if i > 0 {
+				ev.Gap = e.Time.Sub(trace[i-1].Time)
+			}
+		}
+		ev.Latency, _ = e.Latency()
+		ev.Error = isError(e)
+		if ev.Error {
+			t.Errors++
+		}
+		t.Events = append(t.Events, ev)
+	}
+	return t
+}
+
+// Duration returns the time from the first to the last event.
+func (t *Timeline) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// serviceName returns the LAPP of an entry, or the name of its log file.
+func serviceName(e *Entry) string {
+	if e.App != "" {
+		return e.App
+	}
+	name := filepath.Base(e.File)
+	if log := tracing.BackupOf(name); log != "" {
+		name = log
+	}
+	name = strings.TrimSuffix(name, ".gz")
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
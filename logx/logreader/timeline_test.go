@@ -0,0 +1,87 @@
+package logreader_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/logreader"
+)
+
+func TestTimeline(t *testing.T) {
+	var entries []*logreader.Entry
+	for _, c := range []struct{ file, line string }{
+		{"/logs/order.log", `{"L":"INFO","T":"2024-05-01T10:00:00.000Z","M":"create","LAPP":"order","TRACE_ID":"t1"}`},
+		{"/logs/order.log", `{"L":"INFO","T":"2024-05-01T10:00:00.010Z","M":"other trace","LAPP":"order","TRACE_ID":"t2"}`},
+		{"/logs/order.log", `{"L":"INFO","T":"2024-05-01T10:00:01.500Z","M":"done","LAPP":"order","TRACE_ID":"t1","httpRequest":{"status":502,"latency":"1.5s"}}`},
+		{"/logs/pay-2024-05-01T00-00-00.000.log.gz", `{"L":"INFO","T":"2024-05-01T10:00:00.100Z","M":"charge","TRACE_ID":"t1","grpc.latency":200000000}`},
+		{"/logs/pay.log", `{"L":"ERROR","T":"2024-05-01T10:00:00.100Z","M":"declined","TRACE_ID":"4bf92f3577b34da6a3ce929d0e0e4736","REQUEST_ID":"t1"}`},
+	} {
+		e, err := logreader.Parse([]byte(c.line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.File = c.file
+		entries = append(entries, e)
+	}
+
+	// an entry of a span has the trace ID as its REQUEST_ID
+	tl := logreader.NewTimeline("t1", entries)
+	if len(tl.Events) != 4 || tl.Errors != 2 || tl.Duration() != 1500*time.Millisecond {
+		t.Fatalf("unexpected timeline %+v", tl)
+	}
+	if !reflect.DeepEqual(tl.Services, []string{"order", "pay"}) {
+		t.Errorf("Services = %v", tl.Services)
+	}
+
+	var messages []string
+	for _, ev := range tl.Events {
+		messages = append(messages, ev.Message)
+	}
+	if !reflect.DeepEqual(messages, []string{"create", "charge", "declined", "done"}) {
+		t.Errorf("events are not in order: %v", messages)
+	}
+
+	charge, declined, done := tl.Events[1], tl.Events[2], tl.Events[3]
+	if charge.Lane != 1 || charge.Offset != 100*time.Millisecond || charge.Gap != 100*time.Millisecond ||
+		charge.Latency != 200*time.Millisecond || charge.Error {
+		t.Errorf("unexpected event %+v", charge)
+	}
+	if !declined.Error || declined.Gap != 0 {
+		t.Errorf("unexpected event %+v", declined)
+	}
+	if done.Lane != 0 || !done.Error || done.Latency != 1500*time.Millisecond || done.Status() != 502 {
+		t.Errorf("unexpected event %+v", done)
+	}
+
+	if tl := logreader.NewTimeline("missing", entries); len(tl.Events) != 0 || tl.Duration() != 0 {
+		t.Errorf("unexpected timeline %+v", tl)
+	}
+}
+
+func TestTimelineNoTime(t *testing.T) {
+	var entries []*logreader.Entry
+	for _, line := range []string{
+		`{"L":"INFO","M":"no time","LAPP":"order","TRACE_ID":"t1"}`,
+		`{"L":"INFO","T":"2024-05-01T10:00:00.000Z","M":"create","LAPP":"order","TRACE_ID":"t1"}`,
+		`{"L":"INFO","T":"2024-05-01T10:00:00.200Z","M":"done","LAPP":"order","TRACE_ID":"t1"}`,
+	} {
+		e, err := logreader.Parse([]byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+
+	// the entry without a time is last and does not move the start
+	tl := logreader.NewTimeline("t1", entries)
+	if len(tl.Events) != 3 || tl.Duration() != 200*time.Millisecond || !tl.Start.Equal(entries[1].Time) {
+		t.Fatalf("unexpected timeline %+v", tl)
+	}
+	if last := tl.Events[2]; last.Message != "no time" || last.Offset != 0 || last.Gap != 0 {
+		t.Errorf("unexpected event %+v", last)
+	}
+	if done := tl.Events[1]; done.Message != "done" || done.Offset != 200*time.Millisecond {
+		t.Errorf("unexpected event %+v", done)
+	}
+}
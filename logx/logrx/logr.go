@@ -0,0 +1,119 @@
+// Package logrx provides a logr.LogSink which logs through logx.
+package logrx
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/kakabei/kfgolib/logx"
+	"go.uber.org/zap"
+)
+
+// sink implements logr.LogSink. V(0) is logged at level Info and higher
+// verbosity at level Debug. Without a base, it logs on the global logger at
+// the time of each call, named by name.
+type sink struct {
+	base   *logx.VLogger
+	log    *logx.VLogger
+	name   string
+	depth  int
+	fields []zap.Field
+}
+
+// New returns a logr.Logger which logs on l, or on the global logger at the
+// time of each call if l is nil, so it follows SetConfig and ReplaceLogger.
+func New(l *logx.VLogger) logr.Logger {
+	return logr.New(NewLogSink(l))
+}
+
+// NewLogSink returns a logr.LogSink which logs on l, or on the global logger
+// at the time of each call if l is nil.
+func NewLogSink(l *logx.VLogger) logr.LogSink {
+	if l == nil {
+		return &sink{}
+	}
+	return &sink{base: l, log: l.AddCallerSkip(1)}
+}
+
+// logger returns the logger of the calls.
+func (s *sink) logger() *logx.VLogger {
+	if s.base != nil {
+		return s.log
+	}
+	l := logx.AddCallerSkip(1 + s.depth)
+	if s.name != "" {
+		l = l.Named(s.name)
+	}
+	return l
+}
+
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.depth += info.CallDepth
+	if s.base != nil {
+		s.log = s.base.AddCallerSkip(1 + s.depth)
+	}
+}
+
+func verbosityLevel(v int) string {
+	if v <= 0 {
+		return "info"
+	}
+	return "debug"
+}
+
+func (s *sink) Enabled(level int) bool {
+	return s.logger().Enabled(verbosityLevel(level))
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.logger().ErrAt(verbosityLevel(level), nil, msg, s.zapFields(keysAndValues)...)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.logger().Err(err, msg, s.zapFields(keysAndValues)...)
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	c := *s
+	c.fields = s.zapFields(keysAndValues)
+	return &c
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	c := *s
+	if s.base == nil {
+		// the names are joined like zap.Logger.Named
+		c.name = name
+		if s.name != "" {
+			c.name = s.name + "." + name
+		}
+		return &c
+	}
+	c.base = s.base.Named(name)
+	c.log = s.log.Named(name)
+	return &c
+}
+
+// WithCallDepth implements logr.CallDepthLogSink interface.
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	c := *s
+	c.depth += depth
+	if s.base != nil {
+		c.log = s.base.AddCallerSkip(1 + c.depth)
+	}
+	return &c
+}
+
+func (s *sink) zapFields(keysAndValues []interface{}) []zap.Field {
+	fields := make([]zap.Field, 0, len(s.fields)+len(keysAndValues)/2)
+	fields = append(fields, s.fields...)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		if i+1 == len(keysAndValues) {
+			fields = append(fields, zap.Any("ignored", key))
+			break
+		}
+		fields = append(fields, zap.Any(key, keysAndValues[i+1]))
+	}
+	return fields
+}
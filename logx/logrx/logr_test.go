@@ -0,0 +1,54 @@
+package logrx_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx"
+	"github.com/kakabei/kfgolib/logx/logrx"
+	"github.com/kakabei/kfgolib/logx/logxtest"
+)
+
+func TestLogr(t *testing.T) {
+	l, logs := logxtest.New(logx.WithGlobalCallerSkip(1))
+	log := logrx.New(l).WithName("controller").WithValues("tenant", "t1")
+
+	log.Info("reconciled", "items", 3)
+	log.V(1).Info("verbose")
+	log.Error(errors.New("conflict"), "update failed", "retry", true)
+
+	entries := logs.All()
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	for i, level := range []string{"info", "debug", "error"} {
+		e := entries[i]
+		if e.Level.String() != level || e.LoggerName != "controller" || e.ContextMap()["tenant"] != "t1" {
+			t.Errorf("unexpected entry %v %v", e.Entry, e.ContextMap())
+		}
+		if filepath.Base(e.Caller.File) != "logr_test.go" {
+			t.Errorf("caller is %s, want logr_test.go", e.Caller.File)
+		}
+	}
+	if entries[0].ContextMap()["items"] != int64(3) || entries[2].ContextMap()["error"] == nil {
+		t.Errorf("unexpected fields %v %v", entries[0].ContextMap(), entries[2].ContextMap())
+	}
+
+}
+
+func TestLogrGlobal(t *testing.T) {
+	// created before the global logger is replaced
+	log := logrx.New(nil).WithName("controller").WithName("pods")
+	logs := logxtest.ReplaceGlobal(t)
+
+	log.Info("reconciled")
+
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].LoggerName != "controller.pods" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+	if filepath.Base(entries[0].Caller.File) != "logr_test.go" {
+		t.Errorf("caller is %s, want logr_test.go", entries[0].Caller.File)
+	}
+}
@@ -0,0 +1,161 @@
+// Package logxtest provides a logx.VLogger which keeps the logged entries in
+// memory, so tests can assert on what was logged.
+package logxtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx"
+	"github.com/kakabei/kfgolib/logx/tracing"
+
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Entry is a logged entry with its fields.
+type Entry = observer.LoggedEntry
+
+// Logs is a collection of logged entries.
+type Logs struct {
+	observed *observer.ObservedLogs
+}
+
+// NewConfig returns the config used by New, entries of all levels are kept
+// and nothing is written to files or the console.
+func NewConfig() logx.Config {
+	config := logx.NewStdConfig()
+	config.EnableConsole = false
+	config.EnableSourceIP = false
+	config.AppName = "logxtest"
+	return config
+}
+
+// New returns a logger which keeps every entry in the returned Logs.
+func New(opts ...logx.Option) (*logx.VLogger, *Logs) {
+	config, logs := newObservedConfig(opts...)
+	return logx.NewLogger(config), logs
+}
+
+func newObservedConfig(opts ...logx.Option) (logx.Config, *Logs) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	config := NewConfig()
+	for _, opt := range opts {
+		opt((*tracing.Config)(&config))
+	}
+	config.WrapCore = func(zapcore.Core) zapcore.Core {
+		return core
+	}
+	return config, &Logs{observed}
+}
+
+// ReplaceGlobal replaces the global logger with an in-memory logger until the
+// test finishes.
+func ReplaceGlobal(t testing.TB, opts ...logx.Option) *Logs {
+	t.Helper()
+	config, logs := newObservedConfig(opts...)
+	t.Cleanup(logx.SetConfig(config))
+	return logs
+}
+
+// FailOnError fails the test when it finishes if logs contains entries at
+// level Error or above. Entries removed by TakeAll are expected and ignored.
+func FailOnError(t testing.TB, logs *Logs) {
+	t.Helper()
+	t.Cleanup(func() {
+		logs.AssertNoErrors(t)
+	})
+}
+
+// AssertNoErrors fails the test if logs contains entries at level Error or
+// above.
+func (l *Logs) AssertNoErrors(t testing.TB) {
+	t.Helper()
+	for _, e := range l.FilterMinLevel("error").All() {
+		t.Errorf("unexpected %s log: %s %v", e.Level.CapitalString(), e.Message, e.ContextMap())
+	}
+}
+
+// Len returns the number of entries.
+func (l *Logs) Len() int {
+	return l.observed.Len()
+}
+
+// All returns a copy of all the entries.
+func (l *Logs) All() []Entry {
+	return l.observed.All()
+}
+
+// TakeAll returns a copy of all the entries and removes them.
+func (l *Logs) TakeAll() []Entry {
+	return l.observed.TakeAll()
+}
+
+// Messages returns the messages of all the entries.
+func (l *Logs) Messages() []string {
+	entries := l.All()
+	messages := make([]string, 0, len(entries))
+	for _, e := range entries {
+		messages = append(messages, e.Message)
+	}
+	return messages
+}
+
+// FilterLevel returns the entries logged at level.
+func (l *Logs) FilterLevel(level string) *Logs {
+	lvl := parseLevel(level)
+	return l.filter(func(e Entry) bool {
+		return e.Level == lvl
+	})
+}
+
+// FilterMinLevel returns the entries logged at or above level.
+func (l *Logs) FilterMinLevel(level string) *Logs {
+	lvl := parseLevel(level)
+	return l.filter(func(e Entry) bool {
+		return e.Level >= lvl
+	})
+}
+
+// FilterMessage returns the entries whose message contains substr.
+func (l *Logs) FilterMessage(substr string) *Logs {
+	return l.filter(func(e Entry) bool {
+		return strings.Contains(e.Message, substr)
+	})
+}
+
+// FilterFieldKey returns the entries which have a field named key.
+func (l *Logs) FilterFieldKey(key string) *Logs {
+	return l.filter(func(e Entry) bool {
+		_, ok := e.ContextMap()[key]
+		return ok
+	})
+}
+
+// FilterField returns the entries which have a field named key with value.
+// Values are compared by their default format, so 100 matches int64(100).
+func (l *Logs) FilterField(key string, value interface{}) *Logs {
+	want := fmt.Sprint(value)
+	return l.filter(func(e Entry) bool {
+		v, ok := e.ContextMap()[key]
+		return ok && fmt.Sprint(v) == want
+	})
+}
+
+// FilterTraceID returns the entries logged with traceID.
+func (l *Logs) FilterTraceID(traceID string) *Logs {
+	return l.FilterField("TRACE_ID", traceID)
+}
+
+func (l *Logs) filter(keep func(Entry) bool) *Logs {
+	return &Logs{l.observed.Filter(keep)}
+}
+
+func parseLevel(level string) zapcore.Level {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return zapcore.InfoLevel
+	}
+	return lvl
+}
@@ -0,0 +1,55 @@
+package logxtest_test
+
+import (
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx"
+	"github.com/kakabei/kfgolib/logx/logxtest"
+)
+
+func TestNew(t *testing.T) {
+	logger, logs := logxtest.New()
+	logxtest.FailOnError(t, logs)
+
+	ctx := logx.NewTraceCtx("test-trace")
+	logger.DebugContext(ctx, "debug message")
+	logger.With("user_id", 100).InfofContext(ctx, "user %d logged in", 100)
+	logger.Warn("no trace")
+	logger.Error("expected error")
+
+	if logs.Len() != 4 {
+		t.Fatalf("got %d entries, want 4", logs.Len())
+	}
+	if n := logs.FilterTraceID("test-trace").Len(); n != 2 {
+		t.Errorf("got %d entries for trace, want 2", n)
+	}
+	if n := logs.FilterField("user_id", 100).FilterMessage("logged in").Len(); n != 1 {
+		t.Errorf("got %d entries for user_id, want 1", n)
+	}
+	if n := logs.FilterLevel("warn").Len(); n != 1 {
+		t.Errorf("got %d warn entries, want 1", n)
+	}
+	if n := logs.FilterFieldKey("LAPP").Len(); n != 4 {
+		t.Errorf("got %d entries with LAPP, want 4", n)
+	}
+
+	// the error was expected, take it so FailOnError passes.
+	if errs := logs.FilterMinLevel("error").Messages(); len(errs) != 1 || errs[0] != "expected error" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	logs.TakeAll()
+}
+
+func TestReplaceGlobal(t *testing.T) {
+	logs := logxtest.ReplaceGlobal(t)
+
+	logx.InfoContext(logx.NewTraceCtx("global-trace"), "global message")
+
+	entries := logs.FilterTraceID("global-trace").All()
+	if len(entries) != 1 || entries[0].Message != "global message" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+	if entries[0].Caller.File == "" || !entries[0].Caller.Defined {
+		t.Errorf("entry has no caller")
+	}
+}
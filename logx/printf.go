@@ -0,0 +1,35 @@
+package logx
+
+import (
+	"fmt"
+)
+
+// PrintfLogger is the logger interface expected by many libraries.
+type PrintfLogger interface {
+	Printf(format string, args ...interface{})
+}
+
+type printfLogger struct {
+	log   *VLogger
+	level string
+}
+
+// NewPrintfLogger returns a PrintfLogger which logs on the global logger at
+// the time of each call at the specified level, so it follows SetConfig and
+// ReplaceLogger.
+func NewPrintfLogger(level string) PrintfLogger {
+	return printfLogger{level: level}
+}
+
+// PrintfLogger returns a PrintfLogger which logs at the specified level.
+func (l *VLogger) PrintfLogger(level string) PrintfLogger {
+	return printfLogger{log: l.AddCallerSkip(1), level: level}
+}
+
+func (p printfLogger) Printf(format string, args ...interface{}) {
+	log := p.log
+	if log == nil {
+		log = AddCallerSkip(1)
+	}
+	log.ErrAtContext(oldCtx, p.level, nil, fmt.Sprintf(format, args...))
+}
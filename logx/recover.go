@@ -0,0 +1,121 @@
+package logx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/kakabei/kfgolib/common"
+	"github.com/kakabei/kfgolib/logx/tracing"
+
+	"go.uber.org/zap"
+)
+
+// Recover is a HTTP middleware which recovers from panics in next. The panic
+// value, stacktrace and request are logged with the request's trace ID, and
+// a common.ErrorResp is sent back with status 500 if next has not written
+// the response yet. Without a trace ID in the context or the X-Request-ID
+// header, a new one is generated.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoverWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			// http.ErrAbortHandler is used to abort a response on purpose.
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			ctx := r.Context()
+			traceID := GetTraceID(ctx)
+			if traceID == "" {
+				traceID = r.Header.Get(tracing.KeyXRequestID)
+				if traceID == "" {
+					traceID = tracing.NewTraceID()
+				}
+				ctx = WithTraceID(ctx, traceID)
+			}
+
+			msg := http.StatusText(http.StatusInternalServerError)
+			ret := &HTTPRet{RetCode: http.StatusInternalServerError, RetMsg: msg, RetRequestID: traceID}
+			res := &http.Response{StatusCode: http.StatusInternalServerError}
+			if rw.status != 0 {
+				// the status and a part of the body are already sent
+				res.StatusCode = rw.status
+			}
+			logPanic(ctx, v, HTTP(NewHTTP(r, res, ret)))
+			if rw.status != 0 {
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(common.ErrorResp{
+				Ret: common.HTTPCommonHead{
+					Code:      http.StatusInternalServerError,
+					Msg:       msg,
+					RequestID: traceID,
+				},
+			})
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// recoverWriter records the status of the response written by the handler.
+type recoverWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *recoverWriter) WriteHeader(code int) {
+	// informational headers are followed by the final status
+	if w.status == 0 && code >= http.StatusOK {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recoverWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush flushes the response if the underlying writer supports it.
+func (w *recoverWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *recoverWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Go runs fn in a new goroutine. A panic in fn is recovered and logged with
+// the trace ID of ctx instead of crashing the process.
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				logPanic(ctx, v)
+			}
+		}()
+		fn(ctx)
+	}()
+}
+
+func logPanic(ctx context.Context, v interface{}, fields ...zap.Field) {
+	fields = append(fields, zap.Any("panic", v), zap.Stack("stacktrace"))
+	GetLogger().WithField(fields...).Error(ctx, "panic recovered")
+}
@@ -0,0 +1,107 @@
+package logx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kakabei/kfgolib/common"
+	"github.com/kakabei/kfgolib/logx/tracing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// captureEntries replaces the global logger with one which sends every entry
+// to the returned channel.
+func captureEntries(t *testing.T) <-chan map[string]interface{} {
+	ch := make(chan map[string]interface{}, 16)
+	config := NewStdConfig()
+	config.EnableConsole = false
+	config.Hooks = []tracing.Hook{func(ent zapcore.Entry, fields []zapcore.Field) error {
+		enc := zapcore.NewMapObjectEncoder()
+		for _, f := range fields {
+			f.AddTo(enc)
+		}
+		enc.Fields["M"] = ent.Message
+		ch <- enc.Fields
+		return nil
+	}}
+	t.Cleanup(SetConfig(config))
+	return ch
+}
+
+func TestRecover(t *testing.T) {
+	entries := captureEntries(t)
+
+	handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(tracing.KeyXRequestID, "recover-trace")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status is %d, want 500", rec.Code)
+	}
+	resp := common.ErrorResp{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Ret.Code != http.StatusInternalServerError || resp.Ret.RequestID != "recover-trace" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	e := <-entries
+	if e["M"] != "panic recovered" || e["panic"] != "boom" || e["TRACE_ID"] != "recover-trace" {
+		t.Errorf("unexpected entry %v", e)
+	}
+	if !strings.Contains(e["stacktrace"].(string), "recover_test.go") {
+		t.Errorf("stacktrace does not contain the panic site: %v", e["stacktrace"])
+	}
+	if req := e["httpRequest"].(map[string]interface{}); req["status"] != 500 || req["requestUrl"] != "/panic" {
+		t.Errorf("unexpected httpRequest %v", req)
+	}
+}
+
+func TestGo(t *testing.T) {
+	entries := captureEntries(t)
+
+	Go(NewTraceCtx("go-trace"), func(ctx context.Context) {
+		panic("goroutine boom")
+	})
+
+	e := <-entries
+	if e["panic"] != "goroutine boom" || e["TRACE_ID"] != "go-trace" {
+		t.Errorf("unexpected entry %v", e)
+	}
+}
+
+func TestRecoverWritten(t *testing.T) {
+	entries := captureEntries(t)
+
+	handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("partial"))
+		panic("boom after write")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/written", nil))
+
+	// the sent response is not followed by the error body
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "partial" {
+		t.Errorf("unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+
+	// a trace ID is generated without X-Request-ID
+	e := <-entries
+	if e["panic"] != "boom after write" || len(e["TRACE_ID"].(string)) != 32 {
+		t.Errorf("unexpected entry %v", e)
+	}
+	if req := e["httpRequest"].(map[string]interface{}); req["status"] != http.StatusAccepted {
+		t.Errorf("unexpected httpRequest %v", req)
+	}
+}
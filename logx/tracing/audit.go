@@ -0,0 +1,412 @@
+package tracing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// EnvAuditKey is the environment variable of the audit HMAC key, which is
+	// used if Config.AuditKey is empty.
+	EnvAuditKey = "LOGX_AUDIT_KEY"
+
+	// ErrAuditDisabled is returned by Audit if Config.EnableAudit is not set.
+	ErrAuditDisabled = errors.New("audit log is disabled")
+)
+
+const auditHashKey = `,"HASH":"`
+
+// auditReservedKeys are the keys of the chain, which are not allowed in the
+// fields of an entry.
+var auditReservedKeys = map[string]bool{"SEQ": true, "PREV_HASH": true, "HASH": true}
+
+// auditWriterKey is the key of the writer of an audit file and HMAC key.
+type auditWriterKey struct {
+	filename string
+	key      string
+}
+
+var (
+	_auditMu      sync.Mutex
+	_auditWriters = map[auditWriterKey]*auditWriter{}
+)
+
+// errAuditClosed is returned by auditWriter.write after it is closed.
+var errAuditClosed = errors.New("audit file is closed")
+
+// auditWriter appends the entries of an audit file, each entry has the
+// sequence number SEQ, the hash of the previous entry PREV_HASH and its own
+// hash HASH, the HMAC-SHA256 of the entry up to HASH.
+type auditWriter struct {
+	wk   auditWriterKey
+	mu   sync.Mutex
+	file *os.File // nil after close
+	key  []byte
+	enc  zapcore.Encoder
+	seq  int64
+	prev string
+}
+
+// auditWriterKeyOf returns the key of the writer of the config.
+func auditWriterKeyOf(config Config) (auditWriterKey, error) {
+	filename, err := filepath.Abs(config.AuditFile)
+	if err != nil {
+		return auditWriterKey{}, err
+	}
+	return auditWriterKey{filename, string(auditKey(config.AuditKey))}, nil
+}
+
+// openAuditWriter returns the writer of the audit file of the config, which
+// is shared by the loggers of the same file and key. The chain continues
+// from the last entry of the file, a file can not be open with two keys.
+func openAuditWriter(config Config) (*auditWriter, error) {
+	wk, err := auditWriterKeyOf(config)
+	if err != nil {
+		return nil, err
+	}
+	filename := wk.filename
+
+	_auditMu.Lock()
+	defer _auditMu.Unlock()
+	if w, ok := _auditWriters[wk]; ok {
+		return w, nil
+	}
+
+	key := []byte(wk.key)
+	if len(key) == 0 {
+		return nil, errors.New("no audit key, set AuditKey or " + EnvAuditKey)
+	}
+	for k := range _auditWriters {
+		if k.filename == filename {
+			return nil, fmt.Errorf("audit file %s is open with another key", filename)
+		}
+	}
+	seq, prev, err := lastAuditEntry(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "T",
+		MessageKey:     "M",
+		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		EncodeDuration: zapcore.NanosDurationEncoder,
+		LineEnding:     "\n",
+	}
+	w := &auditWriter{
+		wk:   wk,
+		file: f,
+		key:  key,
+		enc:  zapcore.NewJSONEncoder(encoderConfig),
+		seq:  seq,
+		prev: prev,
+	}
+	_auditWriters[wk] = w
+	return w, nil
+}
+
+// auditCloser closes the audit writer of the config of a logger, a later
+// Audit opens it again.
+type auditCloser struct {
+	config Config
+}
+
+func (c auditCloser) Close() error {
+	wk, err := auditWriterKeyOf(c.config)
+	if err != nil {
+		return nil
+	}
+	_auditMu.Lock()
+	w := _auditWriters[wk]
+	_auditMu.Unlock()
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.close()
+}
+
+// close removes the writer from _auditWriters and closes its file, the
+// loggers which share it open the file again by their next Audit. It must
+// be called with mu held.
+func (w *auditWriter) close() error {
+	_auditMu.Lock()
+	if _auditWriters[w.wk] == w {
+		delete(_auditWriters, w.wk)
+	}
+	_auditMu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func auditKey(key string) []byte {
+	if key == "" {
+		key = os.Getenv(EnvAuditKey)
+	}
+	return []byte(key)
+}
+
+// auditEntry is the chain of an audit entry.
+type auditEntry struct {
+	Seq      int64  `json:"SEQ"`
+	PrevHash string `json:"PREV_HASH"`
+	Hash     string `json:"HASH"`
+}
+
+// lastAuditEntry returns SEQ and HASH of the last entry of the file. An
+// unterminated last line, which is left by a crash or a failed write, is
+// removed and reported, so the chain continues from the entry before it.
+func lastAuditEntry(filename string) (int64, string, error) {
+	last, end, partial, err := readLastLine(filename)
+	if err != nil {
+		return 0, "", err
+	}
+	if partial > 0 {
+		if err := os.Truncate(filename, end); err != nil {
+			return 0, "", err
+		}
+		fmt.Fprintf(os.Stderr, "logx: audit file %s: removed an incomplete last entry of %d bytes\n", filename, partial)
+	}
+	if last == nil {
+		return 0, "", nil
+	}
+
+	var entry auditEntry
+	if err := json.Unmarshal(last, &entry); err != nil {
+		return 0, "", fmt.Errorf("invalid last audit entry of %s: %w", filename, err)
+	}
+	return entry.Seq, entry.Hash, nil
+}
+
+// readLastLine returns the last terminated line of the file which is not
+// empty, the end of the terminated lines and the size of the unterminated
+// rest.
+func readLastLine(filename string) (last []byte, end int64, partial int, err error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, 0, 0, nil
+	}
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return last, end, len(line), nil
+		}
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		end += int64(len(line))
+		if len(bytes.TrimSpace(line)) > 0 {
+			last = line
+		}
+	}
+}
+
+func (w *auditWriter) write(fields []zap.Field) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return errAuditClosed
+	}
+
+	seq := w.seq + 1
+	fields = append([]zap.Field{zap.Int64("SEQ", seq)}, fields...)
+	fields = append(fields, zap.String("PREV_HASH", w.prev))
+
+	buf, err := w.enc.EncodeEntry(zapcore.Entry{Time: time.Now(), Message: "audit"}, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	// the entry is {...,"PREV_HASH":"..."}\n, the hash is inserted before }
+	line := bytes.TrimSuffix(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("}"))
+	hash := auditHash(w.key, line)
+	out := make([]byte, 0, len(line)+len(auditHashKey)+len(hash)+3)
+	out = append(out, line...)
+	out = append(out, auditHashKey...)
+	out = append(out, hash...)
+	out = append(out, "\"}\n"...)
+
+	if _, err := w.file.Write(out); err != nil {
+		// a part of the entry may be written, the next Audit opens the file
+		// again and removes it
+		w.close()
+		return err
+	}
+	w.seq, w.prev = seq, hash
+	return nil
+}
+
+// syncAudit flushes the audit file of the config if it is open.
+func syncAudit(config Config) error {
+	if !config.EnableAudit {
+		return nil
+	}
+	wk, err := auditWriterKeyOf(config)
+	if err != nil {
+		return err
+	}
+	_auditMu.Lock()
+	w := _auditWriters[wk]
+	_auditMu.Unlock()
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+func auditHash(key []byte, line []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(line)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Audit appends an entry to the audit file, see Config.EnableAudit. Unlike
+// the other log calls, it returns the error if the entry can not be written.
+// The fields must not be named SEQ, PREV_HASH or HASH, which are the keys of
+// the chain. The entry has the fields of the context, but not the fields
+// added by With and WithField, which only go to the sinks.
+func (l *VLogger) Audit(ctx context.Context, actor, action, resource, outcome string, fields ...zap.Field) error {
+	if !l.config.EnableAudit {
+		return ErrAuditDisabled
+	}
+
+	all := make([]zap.Field, 0, len(fields)+8)
+	all = append(all, zap.String("LAPP", l.config.AppName))
+	all = append(all, l.getFields(ctx)...)
+	all = append(all,
+		zap.String("ACTOR", actor),
+		zap.String("ACTION", action),
+		zap.String("RESOURCE", resource),
+		zap.String("OUTCOME", outcome),
+	)
+	all = append(all, fields...)
+	for _, f := range all {
+		if auditReservedKeys[f.Key] {
+			return fmt.Errorf("audit field %s is reserved", f.Key)
+		}
+	}
+
+	for {
+		w, err := openAuditWriter(l.config)
+		if err != nil {
+			return err
+		}
+		// the writer may be closed by a logger sharing it meanwhile
+		if err := w.write(all); err != errAuditClosed {
+			return err
+		}
+	}
+}
+
+// AuditError is returned by VerifyAudit for the first entry which breaks the
+// chain.
+type AuditError struct {
+	// Line is the line number of the entry in the file
+	Line int
+
+	// Seq is the expected sequence number of the entry
+	Seq int64
+
+	Reason string
+}
+
+func (e *AuditError) Error() string {
+	return "audit entry at line " + strconv.Itoa(e.Line) + " (seq " + strconv.FormatInt(e.Seq, 10) + "): " + e.Reason
+}
+
+// VerifyAudit verifies the chain of the audit entries read from r with the
+// HMAC key, it returns the number of verified entries. A modified, deleted,
+// inserted or reordered entry is reported as an *AuditError. Entries removed
+// from the end of the file can not be detected.
+func VerifyAudit(r io.Reader, key []byte) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	var (
+		n      int
+		lineNo int
+		seq    int64
+		prev   string
+	)
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var entry auditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return n, &AuditError{lineNo, seq + 1, "invalid json: " + err.Error()}
+		}
+		i := bytes.LastIndex(line, []byte(auditHashKey))
+		if i < 0 {
+			return n, &AuditError{lineNo, seq + 1, "no hash"}
+		}
+		if !hmac.Equal([]byte(auditHash(key, line[:i])), []byte(entry.Hash)) {
+			return n, &AuditError{lineNo, seq + 1, "hash mismatch, the entry is modified or the key is wrong"}
+		}
+		if entry.Seq != seq+1 {
+			return n, &AuditError{lineNo, seq + 1, "unexpected seq " + strconv.FormatInt(entry.Seq, 10) + ", entries are deleted or reordered"}
+		}
+		if entry.PrevHash != prev {
+			return n, &AuditError{lineNo, seq + 1, "previous hash mismatch, entries are deleted or reordered"}
+		}
+
+		seq, prev = entry.Seq, entry.Hash
+		n++
+	}
+	return n, scanner.Err()
+}
+
+// VerifyAuditFile verifies the audit file, see VerifyAudit.
+func VerifyAuditFile(filename string, key []byte) (int, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return VerifyAudit(f, key)
+}
@@ -0,0 +1,208 @@
+package tracing_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+	"go.uber.org/zap"
+)
+
+const auditKey = "audit-secret"
+
+func writeAudit(t *testing.T, filename string, n int) {
+	t.Helper()
+	logger := tracing.NewLogger(tracing.Config{AppName: "logx_test"}, tracing.WithAudit(filename, auditKey))
+	ctx := tracing.NewTraceCtx("t1")
+	for i := 0; i < n; i++ {
+		if err := logger.Audit(ctx, "alice", "delete", "order/1", "success", zap.Int("i", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readLines(t *testing.T, filename string) [][]byte {
+	t.Helper()
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.SplitAfter(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+}
+
+func TestAudit(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	writeAudit(t, filename, 3)
+
+	entries := readEntries(t, filename)
+	e := entries[2]
+	for key, want := range map[string]interface{}{
+		"SEQ":      float64(3),
+		"ACTOR":    "alice",
+		"ACTION":   "delete",
+		"RESOURCE": "order/1",
+		"OUTCOME":  "success",
+		"TRACE_ID": "t1",
+		"LAPP":     "logx_test",
+		"i":        float64(2),
+	} {
+		if e[key] != want {
+			t.Errorf("%s = %v, want %v", key, e[key], want)
+		}
+	}
+	if e["PREV_HASH"] != entries[1]["HASH"] || entries[0]["PREV_HASH"] != "" {
+		t.Errorf("entries are not chained: %v", entries)
+	}
+
+	n, err := tracing.VerifyAuditFile(filename, []byte(auditKey))
+	if err != nil || n != 3 {
+		t.Fatalf("VerifyAuditFile = %d, %v", n, err)
+	}
+	if _, err := tracing.VerifyAuditFile(filename, []byte("wrong")); err == nil {
+		t.Errorf("wrong key is not detected")
+	}
+}
+
+func TestAuditDisabled(t *testing.T) {
+	logger := tracing.NewLogger(tracing.Config{})
+	if err := logger.Audit(tracing.NewTraceCtx("t1"), "alice", "login", "app", "success"); err != tracing.ErrAuditDisabled {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestAuditTamper(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	writeAudit(t, filename, 4)
+	lines := readLines(t, filename)
+
+	for name, c := range map[string]struct {
+		lines [][]byte
+		line  int
+	}{
+		"modified": {
+			[][]byte{lines[0], bytes.Replace(lines[1], []byte("alice"), []byte("mallory"), 1), lines[2], lines[3]},
+			2,
+		},
+		"deleted":   {[][]byte{lines[0], lines[2], lines[3]}, 2},
+		"first":     {[][]byte{lines[1], lines[2], lines[3]}, 1},
+		"reordered": {[][]byte{lines[0], lines[2], lines[1], lines[3]}, 2},
+		"inserted":  {[][]byte{lines[0], lines[1], lines[1], lines[2], lines[3]}, 3},
+	} {
+		_, err := tracing.VerifyAudit(bytes.NewReader(bytes.Join(c.lines, nil)), []byte(auditKey))
+		var auditErr *tracing.AuditError
+		if !errors.As(err, &auditErr) || auditErr.Line != c.line {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+}
+
+func TestAuditReopen(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "audit.log")
+	writeAudit(t, filename, 2)
+
+	// the loggers of the same file share the writer, and a file opened for
+	// the first time continues the chain from its last entry
+	writeAudit(t, filepath.Join(dir, "sub", "..", "audit.log"), 1)
+	copied := filepath.Join(dir, "copied.log")
+	b, _ := os.ReadFile(filename)
+	os.WriteFile(copied, b, 0600)
+	writeAudit(t, copied, 2)
+
+	n, err := tracing.VerifyAuditFile(copied, []byte(auditKey))
+	if err != nil || n != 5 {
+		t.Errorf("VerifyAuditFile = %d, %v", n, err)
+	}
+	if lines := readLines(t, copied); !strings.Contains(string(lines[4]), `"SEQ":5`) {
+		t.Errorf("unexpected last entry %s", lines[4])
+	}
+}
+
+func TestAuditClose(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	logger := tracing.NewLogger(tracing.Config{AppName: "logx_test"}, tracing.WithAudit(filename, auditKey))
+	ctx := tracing.NewTraceCtx("t1")
+	if err := logger.Audit(ctx, "alice", "login", "app", "success"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file is open with the key of the logger
+	other := tracing.NewLogger(tracing.Config{AppName: "logx_test"}, tracing.WithAudit(filename, "other-secret"))
+	if err := other.Audit(ctx, "bob", "login", "app", "success"); err == nil || !strings.Contains(err.Error(), "another key") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	for _, key := range []string{"SEQ", "PREV_HASH", "HASH"} {
+		if err := logger.Audit(ctx, "alice", "login", "app", "success", zap.String(key, "x")); err == nil || !strings.Contains(err.Error(), "reserved") {
+			t.Errorf("%s: unexpected error %v", key, err)
+		}
+	}
+
+	// a later entry opens the file again
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Audit(ctx, "alice", "logout", "app", "success"); err != nil {
+		t.Fatal(err)
+	}
+	logger.Close()
+
+	n, err := tracing.VerifyAuditFile(filename, []byte(auditKey))
+	if err != nil || n != 2 {
+		t.Errorf("VerifyAuditFile = %d, %v", n, err)
+	}
+}
+
+func TestAuditPartialEntry(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	writeAudit(t, filename, 2)
+	b, _ := os.ReadFile(filename)
+	lines := readLines(t, filename)
+	// a crash in the middle of the third entry
+	copied := filepath.Join(filepath.Dir(filename), "crashed.log")
+	os.WriteFile(copied, append(b, lines[1][:20]...), 0600)
+
+	writeAudit(t, copied, 1)
+	n, err := tracing.VerifyAuditFile(copied, []byte(auditKey))
+	if err != nil || n != 3 {
+		t.Errorf("VerifyAuditFile = %d, %v", n, err)
+	}
+}
+
+func TestAuditSharedClose(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	config := tracing.Config{AppName: "logx_test"}
+	logger := tracing.NewLogger(config, tracing.WithAudit(filename, auditKey))
+	other := tracing.NewLogger(config, tracing.WithAudit(filename, auditKey))
+	ctx := tracing.NewTraceCtx("t1")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if err := logger.Audit(ctx, "alice", "login", "app", "success"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	// the other logger closes the shared writer meanwhile
+	for i := 0; i < 50; i++ {
+		other.Close()
+	}
+	wg.Wait()
+	logger.Close()
+
+	n, err := tracing.VerifyAuditFile(filename, []byte(auditKey))
+	if err != nil || n != 200 {
+		t.Errorf("VerifyAuditFile = %d, %v", n, err)
+	}
+}
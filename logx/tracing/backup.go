@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupTimeFormat is the time in the names of the backups rotated by
+// lumberjack, e.g. app-2006-01-02T15-04-05.000.log.gz.
+const BackupTimeFormat = "2006-01-02T15-04-05.000"
+
+// BackupFiles returns the backups of a log file, oldest first, which are
+// name-<time>.ext with an optional .gz.
+func BackupFiles(filename string) ([]string, error) {
+	dir := filepath.Dir(filename)
+	base := filepath.Base(filename)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".gz")
+		if !e.Type().IsRegular() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		if isBackupTime(name[len(prefix) : len(name)-len(ext)]) {
+			backups = append(backups, filepath.Join(dir, e.Name()))
+		}
+	}
+	// the time has a fixed width, so the names sort by time
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// BackupOf returns the name of the log file of a backup, or "" if name is
+// not a backup.
+func BackupOf(name string) string {
+	name = strings.TrimSuffix(name, ".gz")
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	i := len(stem) - len(BackupTimeFormat) - 1
+	if i <= 0 || stem[i] != '-' || !isBackupTime(stem[i+1:]) {
+		return ""
+	}
+	return stem[:i] + ext
+}
+
+func isBackupTime(s string) bool {
+	_, err := time.Parse(BackupTimeFormat, s)
+	return err == nil
+}
@@ -3,6 +3,8 @@ package tracing
 import (
 	"encoding/json"
 	"os"
+
+	"go.uber.org/zap/zapcore"
 )
 
 type Config struct {
@@ -39,6 +41,20 @@ type Config struct {
 	// using gzip.
 	Compress bool `json:"compress" yaml:"compress"`
 
+	// EncryptFile determines if the log file is encrypted with AES-GCM,
+	// see SinkConfig.Encrypt
+	EncryptFile bool `json:"encryptfile" yaml:"encryptfile"`
+
+	// EncryptionKey is the AES key of the log file in hex or base64
+	// default is the LOGX_ENCRYPTION_KEY environment variable
+	EncryptionKey string `json:"encryptionkey" yaml:"encryptionkey"`
+
+	// MinFreeSpace, MaxTotalSize and DegradedLevel guard the disk of the log
+	// file if one of them is set, see SinkConfig.DegradedLevel
+	MinFreeSpace  int    `json:"minfreespace" yaml:"minfreespace"`
+	MaxTotalSize  int    `json:"maxtotalsize" yaml:"maxtotalsize"`
+	DegradedLevel string `json:"degradedlevel" yaml:"degradedlevel"`
+
 	// EnableConsole determines if the log should be displayed in stderr.
 	EnableConsole bool `json:"enableconsole" yaml:"enableconsole"`
 
@@ -57,12 +73,13 @@ type Config struct {
 	// log level in console
 	ConsoleLevel string `json:"consolelevel" yaml:"consolelevel"`
 
-	// encoding in log file. Valid values are "json" and
-	// "console"
+	// encoding in log file. Valid values are "json", "console",
+	// "pretty", "logfmt", "ecs" (Elastic Common Schema) and "gelf"
+	// (Graylog Extended Log Format)
 	FileEncodeing string `json:"fileencoding" yaml:"fileencoding"`
 
-	// encoding in console. Valid values are "json" and
-	// "console"
+	// encoding in console. Valid values are the same as
+	// FileEncodeing, colors are only used when stderr is a TTY
 	ConsoleEncodeing string `json:"consoleencoding" yaml:"consoleencoding"`
 
 	// application name
@@ -70,14 +87,139 @@ type Config struct {
 	AppName string `json:"appname" yaml:"appname"`
 
 	// SourceEth determine which eth to get SourceIp
-	// defautl is en0
+	// default is the first up non-loopback interface, the POD_IP
+	// environment variable overrides it
 	SourceEth string `json:"sourceeth" yaml:"sourceeth"`
 
+	// SourceIPv6 determines if SourceIp is an IPv6 address
+	SourceIPv6 bool `json:"sourceipv6" yaml:"sourceipv6"`
+
+	// EnableHostname determines if the log should contain the hostname
+	EnableHostname bool `json:"enablehostname" yaml:"enablehostname"`
+
+	// EnableContainerID determines if the log should contain the container
+	// ID, which is found in /proc/self/cgroup
+	EnableContainerID bool `json:"enablecontainerid" yaml:"enablecontainerid"`
+
+	// EnableK8sMeta determines if the log should contain the Kubernetes pod,
+	// namespace and node names, which are read from the POD_NAME,
+	// POD_NAMESPACE and NODE_NAME environment variables
+	EnableK8sMeta bool `json:"enablek8smeta" yaml:"enablek8smeta"`
+
 	// DisableTraceID disable trace id
 	DisableTraceID bool `json:"disable_trace_id" yaml:"disable_trace_id"`
 
+	// EnableOTLP determines if the log should be exported as OpenTelemetry
+	// log records over OTLP/HTTP JSON
+	EnableOTLP bool `json:"enableotlp" yaml:"enableotlp"`
+
+	// OTLPEndpoint is the OTLP/HTTP logs endpoint
+	// default is http://localhost:4318/v1/logs
+	OTLPEndpoint string `json:"otlpendpoint" yaml:"otlpendpoint"`
+
+	// OTLPHeaders are sent with every export request, e.g. Authorization
+	OTLPHeaders map[string]string `json:"otlpheaders" yaml:"otlpheaders"`
+
+	// log level exported to OTLP
+	OTLPLevel string `json:"otlplevel" yaml:"otlplevel"`
+
+	// OTLPBatchSize is the maximum number of log records in one export
+	// request. default is 512
+	OTLPBatchSize int `json:"otlpbatchsize" yaml:"otlpbatchsize"`
+
+	// OTLPFlushInterval is the maximum time in milliseconds a log record
+	// waits before being exported. default is 1000
+	OTLPFlushInterval int `json:"otlpflushinterval" yaml:"otlpflushinterval"`
+
+	// OTLPQueueSize is the number of log records buffered for export, log
+	// records are dropped when the queue is full. default is 2048
+	OTLPQueueSize int `json:"otlpqueuesize" yaml:"otlpqueuesize"`
+
+	// EnableSpanEvents determines if the log should be added as events to the
+	// recording span of the context
+	EnableSpanEvents bool `json:"enablespanevents" yaml:"enablespanevents"`
+
+	// log level added as span events
+	// default is warn
+	SpanEventLevel string `json:"spaneventlevel" yaml:"spaneventlevel"`
+
 	// GlobalCallerSkip increases the number of callers skipped
 	GlobalCallerSkip int `json:"-" yaml:"-"`
+
+	// StacktraceLevel determines the level at and above which the log should
+	// contain a stacktrace. default is no stacktrace
+	StacktraceLevel string `json:"stacktracelevel" yaml:"stacktracelevel"`
+
+	// HookLevel is the minimum level of entries passed to hooks.
+	// default is info
+	HookLevel string `json:"hooklevel" yaml:"hooklevel"`
+
+	// HookQueueSize is the number of entries buffered for hooks, entries
+	// are dropped when the queue is full. default is 1024
+	HookQueueSize int `json:"hookqueuesize" yaml:"hookqueuesize"`
+
+	// TimeKey, LevelKey, MessageKey, CallerKey, NameKey and StacktraceKey are
+	// the keys of the entry in the json, console and logfmt encodings, "-"
+	// omits the key. defaults are T, L, M, LFILE, logger and stacktrace
+	TimeKey       string `json:"timekey" yaml:"timekey"`
+	LevelKey      string `json:"levelkey" yaml:"levelkey"`
+	MessageKey    string `json:"messagekey" yaml:"messagekey"`
+	CallerKey     string `json:"callerkey" yaml:"callerkey"`
+	NameKey       string `json:"namekey" yaml:"namekey"`
+	StacktraceKey string `json:"stacktracekey" yaml:"stacktracekey"`
+
+	// FunctionKey is the key of the caller's function name.
+	// default is no function name
+	FunctionKey string `json:"functionkey" yaml:"functionkey"`
+
+	// TimeFormat is the format of the time. Valid values are "iso8601",
+	// "rfc3339", "rfc3339nano", "epoch", "epochmillis", "epochnanos" and a
+	// custom layout such as "2006-01-02 15:04:05.000". default is iso8601,
+	// and "15:04:05.000" for the pretty encoding
+	TimeFormat string `json:"timeformat" yaml:"timeformat"`
+
+	// TimeZone is the time zone of the time, e.g. "UTC" or "Asia/Shanghai".
+	// default is the local time zone
+	TimeZone string `json:"timezone" yaml:"timezone"`
+
+	// DurationEncoding is the encoding of durations. Valid values are
+	// "nanos", "millis", "seconds" and "string". default is nanos
+	DurationEncoding string `json:"durationencoding" yaml:"durationencoding"`
+
+	// Sinks are the outputs of the logger in addition to the file and the
+	// console, each with its own level range, encoding and rotation
+	Sinks []SinkConfig `json:"sinks" yaml:"sinks"`
+
+	// EnableAudit determines if Audit appends entries to AuditFile, each
+	// entry has a sequence number and a hash chained to the previous entry
+	EnableAudit bool `json:"enableaudit" yaml:"enableaudit"`
+
+	// AuditFile is the file of the audit log, it is never rotated
+	AuditFile string `json:"auditfile" yaml:"auditfile"`
+
+	// AuditKey is the HMAC key of the audit log hashes
+	// default is the LOGX_AUDIT_KEY environment variable
+	AuditKey string `json:"auditkey" yaml:"auditkey"`
+
+	// MaxFieldLength is the maximum length in bytes of string field values in
+	// every sink, longer values are truncated and marked with TruncatedMarker
+	// default is no maximum length
+	MaxFieldLength int `json:"maxfieldlength" yaml:"maxfieldlength"`
+
+	// CrashFile is the file a crash report with the stacks of all goroutines
+	// is appended to on Fatal, Panic and HandlePanic
+	// default is no crash report
+	CrashFile string `json:"crashfile" yaml:"crashfile"`
+
+	// Hooks are called asynchronously with every entry at or above HookLevel
+	Hooks []Hook `json:"-" yaml:"-"`
+
+	// Golden freezes the time and replaces the PID, IP and caller with stable
+	// placeholders, so the output can be compared with golden files in tests
+	Golden bool `json:"-" yaml:"-"`
+
+	// WrapCore wraps or replaces the core built from the config
+	WrapCore func(zapcore.Core) zapcore.Core `json:"-" yaml:"-"`
 }
 
 func NewDevelopmentConfig(appname string, filename string) Config {
@@ -98,7 +240,7 @@ func NewDevelopmentConfig(appname string, filename string) Config {
 		FileEncodeing:    "json",
 		ConsoleEncodeing: "console",
 		AppName:          appname,
-		SourceEth:        "en0",
+		SourceEth:        "",
 		DisableTraceID:   false,
 	}
 }
@@ -121,7 +263,7 @@ func NewProductionConfig(appname string, filename string) Config {
 		FileEncodeing:    "json",
 		ConsoleEncodeing: "console",
 		AppName:          appname,
-		SourceEth:        "en0",
+		SourceEth:        "",
 		DisableTraceID:   false,
 	}
 }
@@ -144,7 +286,7 @@ func NewStdConfig() Config {
 		FileEncodeing:    "json",
 		ConsoleEncodeing: "console",
 		AppName:          "",
-		SourceEth:        "en0",
+		SourceEth:        "",
 		DisableTraceID:   false,
 	}
 }
@@ -166,3 +308,80 @@ func WithGlobalCallerSkip(n int) Option {
 		c.GlobalCallerSkip = n
 	}
 }
+
+// WithHooks registers hooks which are called asynchronously with the entry
+// and all of its fields, so they never block the caller.
+func WithHooks(hooks ...Hook) Option {
+	return func(c *Config) {
+		c.Hooks = append(c.Hooks, hooks...)
+	}
+}
+
+// WithHookLevel sets the minimum level of entries passed to hooks.
+func WithHookLevel(level string) Option {
+	return func(c *Config) {
+		c.HookLevel = level
+	}
+}
+
+// WithStacktraceLevel attaches a stacktrace to entries at or above level.
+func WithStacktraceLevel(level string) Option {
+	return func(c *Config) {
+		c.StacktraceLevel = level
+	}
+}
+
+// WithWrapCore wraps or replaces the core built from the config.
+func WithWrapCore(f func(zapcore.Core) zapcore.Core) Option {
+	return func(c *Config) {
+		c.WrapCore = f
+	}
+}
+
+// WithGolden makes the output deterministic, see Config.Golden.
+func WithGolden() Option {
+	return func(c *Config) {
+		c.Golden = true
+	}
+}
+
+// WithTimeFormat sets the format and the time zone of the time, see
+// Config.TimeFormat and Config.TimeZone.
+func WithTimeFormat(format string, zone string) Option {
+	return func(c *Config) {
+		c.TimeFormat = format
+		c.TimeZone = zone
+	}
+}
+
+// WithSinks adds outputs to the logger, see Config.Sinks.
+func WithSinks(sinks ...SinkConfig) Option {
+	return func(c *Config) {
+		c.Sinks = append(c.Sinks, sinks...)
+	}
+}
+
+// WithCrashFile writes the crash reports to the file, see Config.CrashFile.
+func WithCrashFile(filename string) Option {
+	return func(c *Config) {
+		c.CrashFile = filename
+	}
+}
+
+// WithDiskGuard sets the minimum free space and the maximum total size in
+// megabytes of the log file, see SinkConfig.MinFreeSpace.
+func WithDiskGuard(minFreeSpace int, maxTotalSize int) Option {
+	return func(c *Config) {
+		c.MinFreeSpace = minFreeSpace
+		c.MaxTotalSize = maxTotalSize
+	}
+}
+
+// WithAudit enables the audit log in the file, see Config.EnableAudit.
+func WithAudit(filename string, key string) Option {
+	return func(c *Config) {
+		c.EnableAudit = true
+		c.AuditFile = filename
+		c.AuditKey = key
+	}
+}
@@ -0,0 +1,208 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// crashHook runs after an entry of Fatal or Panic is written to the cores.
+// It writes the crash report, and syncs all the cores, including the hooks
+// and the OTLP exporter which write asynchronously, before the process exits
+// or panics.
+type crashHook struct {
+	core   zapcore.Core
+	config Config
+	then   zapcore.CheckWriteHook
+}
+
+func (h crashHook) OnWrite(ce *zapcore.CheckedEntry, fields []zapcore.Field) {
+	h.crash(ce.Entry, fields)
+	h.then.OnWrite(ce, fields)
+}
+
+func (h crashHook) crash(ent zapcore.Entry, fields []zapcore.Field) {
+	if h.config.CrashFile != "" {
+		if err := writeCrashFile(h.config, ent, fields); err != nil {
+			fmt.Fprintf(os.Stderr, "logx: crash report: %v\n", err)
+		}
+	}
+	h.core.Sync()
+	syncAudit(h.config)
+}
+
+// closers are the files and connections of the sinks, the async runners and
+// the audit file of a logger, which are shared by the loggers derived from it.
+type closers []io.Closer
+
+func (c closers) Close() error {
+	var errs []error
+	for _, closer := range c {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes the entries like Sync, closes the files and connections of
+// the sinks and the audit file, and stops the goroutines of the hooks and
+// the OTLP exporter. It is shared by the loggers derived by With, a file is
+// opened again by a later write, and the later entries of the hooks and the
+// exporter are dropped.
+func (l *VLogger) Close() error {
+	return errors.Join(l.Sync(), l.closers.Close())
+}
+
+// HandlePanic recovers a panic of the goroutine and calls Repanic with it.
+// Use it with defer at the top of main and goroutines:
+//
+//	defer logger.HandlePanic(ctx)
+func (l *VLogger) HandlePanic(ctx context.Context) {
+	if v := recover(); v != nil {
+		l.Repanic(ctx, v)
+	}
+}
+
+// Repanic logs a recovered panic value at panic level with its stack,
+// writes the crash report and syncs the sinks, then panics again with v.
+func (l *VLogger) Repanic(ctx context.Context, v interface{}) {
+	ce := l.log.Check(zap.PanicLevel, fmt.Sprint(v))
+	if caller, ok := panicCaller(); ok && ce.Caller.Defined {
+		ce.Caller = caller
+	}
+	ce = ce.After(ce.Entry, crashHook{core: l.log.Core(), config: l.config, then: repanic{v}})
+	ce.Write(append(l.getFields(ctx), zap.Any("panic", v), zap.StackSkip("stacktrace", 1))...)
+}
+
+// panicCaller returns the frame which panicked, the first frame out of the
+// runtime after runtime.gopanic.
+func panicCaller() (zapcore.EntryCaller, bool) {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	panicking := false
+	for {
+		f, more := frames.Next()
+		switch {
+		case f.Function == "runtime.gopanic":
+			panicking = true
+		case panicking && !strings.HasPrefix(f.Function, "runtime."):
+			return zapcore.EntryCaller{Defined: true, PC: f.PC, File: f.File, Line: f.Line, Function: f.Function}, true
+		}
+		if !more {
+			return zapcore.EntryCaller{}, false
+		}
+	}
+}
+
+// repanic panics with the recovered value.
+type repanic struct {
+	v interface{}
+}
+
+func (r repanic) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	panic(r.v)
+}
+
+// HandleSignals closes the global logger and the loggers when the process
+// receives SIGTERM or SIGINT, then exits with 128 plus the signal number.
+// An application with its own graceful shutdown should call Close after it
+// instead. The returned function stops the handler.
+func HandleSignals(loggers ...*VLogger) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-ch:
+			_globalMu.RLock()
+			global := _logger
+			_globalMu.RUnlock()
+			global.Close()
+			for _, l := range loggers {
+				l.Close()
+			}
+			code := 1
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			os.Exit(code)
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// writeCrashFile appends a crash report of the entry with the stacks of all
+// goroutines to the crash file, and flushes it to the disk.
+func writeCrashFile(config Config, ent zapcore.Entry, fields []zapcore.Field) error {
+	if err := os.MkdirAll(filepath.Dir(config.CrashFile), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(config.CrashFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(crashReport(config, ent, fields)); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// crashReport returns the report of a crash, in a single buffer so a crash
+// report is written with a single write.
+func crashReport(config Config, ent zapcore.Entry, fields []zapcore.Field) []byte {
+	buf := _bufferPool.Get()
+	defer buf.Free()
+
+	fmt.Fprintf(buf, "=== crash report %s ===\n", ent.Time.Format(time.RFC3339Nano))
+	fmt.Fprintf(buf, "app: %s\npid: %d\nlevel: %s\nmessage: %s\n", config.AppName, os.Getpid(), ent.Level.CapitalString(), ent.Message)
+	if ent.Caller.Defined {
+		fmt.Fprintf(buf, "caller: %s\n", ent.Caller.TrimmedPath())
+	}
+	stack := ent.Stack
+	for _, f := range encodeFields(fields) {
+		if s, ok := f.value.(string); ok && f.key == "stacktrace" {
+			stack = s
+			continue
+		}
+		fmt.Fprintf(buf, "%s: %v\n", f.key, f.value)
+	}
+	if stack != "" {
+		fmt.Fprintf(buf, "\nstacktrace:\n%s\n", stack)
+	}
+
+	fmt.Fprintf(buf, "\ngoroutines:\n")
+	stacks := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(stacks, true)
+		if n < len(stacks) {
+			stacks = stacks[:n]
+			break
+		}
+		stacks = make([]byte, 2*len(stacks))
+	}
+	buf.Write(stacks)
+	buf.AppendString("\n\n")
+
+	return append([]byte(nil), buf.Bytes()...)
+}
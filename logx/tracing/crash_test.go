@@ -0,0 +1,189 @@
+package tracing_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+// recorder is a hook which records the messages, it is called asynchronously
+// so the messages are only seen before a crash if the logger syncs.
+type recorder struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (r *recorder) hook(e zapcore.Entry, _ []zapcore.Field) error {
+	time.Sleep(10 * time.Millisecond)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, e.Message)
+	return nil
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.messages, ",")
+}
+
+func TestPanicSync(t *testing.T) {
+	dir := t.TempDir()
+	crash := filepath.Join(dir, "crash.log")
+	rec := &recorder{}
+	logger := tracing.NewLogger(NewTestConfig(filepath.Join(dir, "app.log")),
+		tracing.WithHooks(rec.hook), tracing.WithCrashFile(crash))
+
+	func() {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Errorf("recovered %v", v)
+			}
+			if got := rec.String(); got != "before,boom" {
+				t.Errorf("hook got %q before the panic propagated", got)
+			}
+		}()
+		ctx := tracing.NewTraceCtx("t1")
+		logger.Info(ctx, "before")
+		logger.Panic(ctx, "boom")
+	}()
+
+	b, err := os.ReadFile(crash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(b)
+	for _, want := range []string{"=== crash report", "app: logx_test", "level: PANIC", "message: boom", "TRACE_ID: t1", "goroutines:"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("crash report without %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestHandlePanic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	logger := tracing.NewLogger(NewTestConfig(filename))
+	errBoom := errors.New("boom")
+
+	func() {
+		defer func() {
+			if v := recover(); v != errBoom {
+				t.Errorf("recovered %v, want the original value", v)
+			}
+		}()
+		defer logger.HandlePanic(tracing.NewTraceCtx("t1"))
+		panic(errBoom)
+	}()
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(b, &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["L"] != "PANIC" || entry["M"] != "boom" || entry["panic"] != "boom" || entry["TRACE_ID"] != "t1" {
+		t.Errorf("unexpected entry %v", entry)
+	}
+	if caller, _ := entry["LFILE"].(string); !strings.Contains(caller, "crash_test.go") {
+		t.Errorf("caller %q is not the panicking function", caller)
+	}
+	if stack, _ := entry["stacktrace"].(string); !strings.Contains(stack, "TestHandlePanic") {
+		t.Errorf("unexpected stacktrace %q", stack)
+	}
+}
+
+func TestFatalSync(t *testing.T) {
+	if dir := os.Getenv("LOGX_FATAL_DIR"); dir != "" {
+		rec := &recorder{}
+		logger := tracing.NewLogger(NewTestConfig(filepath.Join(dir, "app.log")),
+			tracing.WithHooks(func(e zapcore.Entry, f []zapcore.Field) error {
+				rec.hook(e, f)
+				return os.WriteFile(filepath.Join(dir, "hook.log"), []byte(rec.String()), 0644)
+			}),
+			tracing.WithCrashFile(filepath.Join(dir, "crash.log")))
+		logger.Info(tracing.NewTraceCtx("t1"), "before")
+		logger.Fatal(tracing.NewTraceCtx("t1"), "fatal")
+		return
+	}
+
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalSync$")
+	cmd.Env = append(os.Environ(), "LOGX_FATAL_DIR="+dir)
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("exit with %v, want 1", err)
+	}
+
+	if b, _ := os.ReadFile(filepath.Join(dir, "hook.log")); string(b) != "before,fatal" {
+		t.Errorf("hook got %q before the exit", b)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "crash.log")); !strings.Contains(string(b), "message: fatal") {
+		t.Errorf("unexpected crash report %q", b)
+	}
+}
+
+func TestClose(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	closed := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var messages []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			entry := map[string]interface{}{}
+			json.Unmarshal(scanner.Bytes(), &entry)
+			messages = append(messages, entry["M"].(string))
+		}
+		closed <- messages
+	}()
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	logger := tracing.NewLogger(NewTestConfig(filename),
+		tracing.WithSinks(tracing.SinkConfig{Type: "tcp", Address: ln.Addr().String()}))
+	logger.Warn(tracing.NewTraceCtx("t1"), "before close")
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case messages := <-closed:
+		if strings.Join(messages, ",") != "before close" {
+			t.Errorf("unexpected messages %v", messages)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("connection not closed")
+	}
+
+	// the file is opened again by a later write
+	logger.Warn(tracing.NewTraceCtx("t1"), "after close")
+	logger.Sync()
+	if b, _ := os.ReadFile(filename); !strings.Contains(string(b), "after close") {
+		t.Errorf("unexpected file %q", b)
+	}
+}
@@ -0,0 +1,268 @@
+package tracing
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// EnvEncryptionKey is the environment variable of the log file encryption
+// key, which is used if the key of the sink is empty.
+var EnvEncryptionKey = "LOGX_ENCRYPTION_KEY"
+
+// maxFrameSize limits the ciphertext of a frame, a larger length means the
+// file is corrupted or not encrypted.
+const maxFrameSize = 64 << 20
+
+// ParseEncryptionKey parses an AES-128, AES-192 or AES-256 key encoded in
+// hex or base64.
+func ParseEncryptionKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := hex.DecodeString(s); err == nil && validKeySize(len(b)) {
+		return b, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil && validKeySize(len(b)) {
+			return b, nil
+		}
+	}
+	return nil, errors.New("invalid encryption key, want 16, 24 or 32 bytes in hex or base64")
+}
+
+func validKeySize(n int) bool {
+	return n == 16 || n == 24 || n == 32
+}
+
+// encryptionKey returns the key of the sink, or of the EnvEncryptionKey
+// environment variable.
+func encryptionKey(key string) ([]byte, error) {
+	if key == "" {
+		key = os.Getenv(EnvEncryptionKey)
+	}
+	if key == "" {
+		return nil, errors.New("no encryption key, set EncryptionKey or " + EnvEncryptionKey)
+	}
+	return ParseEncryptionKey(key)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+const (
+	// segmentIDSize is the size of the random ID of a segment, from which
+	// the key of the segment is derived.
+	segmentIDSize = 16
+
+	// frameHeaderSize is the length, the segment ID and the sequence number
+	// which precede the sealed data of a frame.
+	frameHeaderSize = 4 + segmentIDSize + 8
+
+	// maxSegmentFrames limits the frames sealed with the key of a segment.
+	maxSegmentFrames = 1 << 32
+)
+
+// segmentKeyLabel is the context of the derivation of the segment keys.
+var segmentKeyLabel = []byte("logx encryption segment")
+
+// newSegmentGCM returns the AES-GCM of a segment, the key of which is the
+// HMAC-SHA256 of its ID with the key, truncated to the size of the key.
+func newSegmentGCM(key []byte, id []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(segmentKeyLabel)
+	mac.Write(id)
+	return newGCM(mac.Sum(nil)[:len(key)])
+}
+
+// frameNonce returns the nonce of the frame seq of a segment, which is
+// unique as the key of a segment is.
+func frameNonce(nonce []byte, seq uint64) []byte {
+	for i := range nonce {
+		nonce[i] = 0
+	}
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+// encryptWriter encrypts every write with AES-GCM into a frame of a 4 byte
+// big-endian length, the segment ID, the 8 byte big-endian sequence number
+// of the frame in the segment and the sealed data. The writer starts a
+// segment with a random ID when it is created, after maxSegmentFrames
+// frames and after a failed write, the frames of a segment are sealed with its own key, a counter
+// nonce and the segment ID and sequence number as additional data. A frame
+// is written with a single write, so a rotated file never splits a frame.
+type encryptWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	key  []byte
+	aead cipher.AEAD
+	id   [segmentIDSize]byte
+	seq  uint64
+	buf  []byte
+}
+
+func newEncryptWriter(w io.Writer, key []byte) (*encryptWriter, error) {
+	// the key is validated before the first write
+	if _, err := newGCM(key); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, key: key}, nil
+}
+
+// newSegment starts a segment with a random ID.
+func (w *encryptWriter) newSegment() error {
+	if _, err := rand.Read(w.id[:]); err != nil {
+		return err
+	}
+	aead, err := newSegmentGCM(w.key, w.id[:])
+	if err != nil {
+		return err
+	}
+	w.aead, w.seq = aead, 0
+	return nil
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.aead == nil || w.seq >= maxSegmentFrames {
+		if err := w.newSegment(); err != nil {
+			return 0, err
+		}
+	}
+
+	size := segmentIDSize + 8 + len(p) + w.aead.Overhead()
+	if cap(w.buf) < 4+size {
+		w.buf = make([]byte, 0, 4+size)
+	}
+	frame := w.buf[:frameHeaderSize]
+	binary.BigEndian.PutUint32(frame, uint32(size))
+	copy(frame[4:], w.id[:])
+	binary.BigEndian.PutUint64(frame[4+segmentIDSize:], w.seq)
+	var nonce [12]byte
+	frame = w.aead.Seal(frame, frameNonce(nonce[:w.aead.NonceSize()], w.seq), p, frame[4:frameHeaderSize])
+
+	if _, err := w.w.Write(frame); err != nil {
+		// the next write starts a segment, so the frame missing from this one
+		// is not a gap, and its sequence number is never reused
+		w.aead = nil
+		return 0, err
+	}
+	w.seq++
+	return len(p), nil
+}
+
+// decryptReader reads the plaintext of the frames of an encryptWriter.
+type decryptReader struct {
+	r     *bufio.Reader
+	key   []byte
+	aead  cipher.AEAD
+	id    []byte
+	seq   uint64
+	frame []byte
+	plain []byte
+	off   int64
+}
+
+// NewDecryptReader returns a reader of the plaintext of an encrypted log
+// file, which may be compressed by the rotation. A frame which fails the
+// authentication, is missing from or out of order in its segment, or is
+// truncated is reported with its offset. A file may begin in the middle of
+// a segment, as the rotation splits them, so the frames removed from the
+// beginning or the end of a file can not be detected.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	if _, err := newGCM(key); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(zr)
+	}
+	return &decryptReader{r: br, key: key}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+func (d *decryptReader) next() error {
+	var header [4]byte
+	if _, err := io.ReadFull(d.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("truncated frame at offset %d", d.off)
+		}
+		return err
+	}
+	size := int(binary.BigEndian.Uint32(header[:]))
+	// the overhead of AES-GCM is 16 bytes for all the key sizes
+	if size < frameHeaderSize-4+16 || size > maxFrameSize {
+		return fmt.Errorf("invalid frame size %d at offset %d, the file is not encrypted or corrupted", size, d.off)
+	}
+
+	if cap(d.frame) < 4+size {
+		d.frame = make([]byte, 4+size)
+	}
+	frame := d.frame[:4+size]
+	copy(frame, header[:])
+	if _, err := io.ReadFull(d.r, frame[4:]); err != nil {
+		return fmt.Errorf("truncated frame at offset %d", d.off)
+	}
+	id, seq := frame[4:4+segmentIDSize], binary.BigEndian.Uint64(frame[4+segmentIDSize:])
+
+	if d.aead != nil && bytes.Equal(id, d.id) {
+		if seq != d.seq+1 {
+			return fmt.Errorf("frame %d at offset %d follows frame %d of its segment, frames are missing or reordered", seq, d.off, d.seq)
+		}
+	} else {
+		// only the first frame of a file may continue a segment
+		if seq != 0 && d.off != 0 {
+			return fmt.Errorf("segment begins with frame %d at offset %d, frames are missing or reordered", seq, d.off)
+		}
+		aead, err := newSegmentGCM(d.key, id)
+		if err != nil {
+			return err
+		}
+		d.aead, d.id = aead, append(d.id[:0], id...)
+	}
+
+	var nonce [12]byte
+	sealed := frame[frameHeaderSize:]
+	plain, err := d.aead.Open(sealed[:0], frameNonce(nonce[:d.aead.NonceSize()], seq), sealed, frame[4:frameHeaderSize])
+	if err != nil {
+		return fmt.Errorf("frame at offset %d: %w", d.off, err)
+	}
+	d.seq = seq
+	d.plain = plain
+	d.off += int64(4 + size)
+	return nil
+}
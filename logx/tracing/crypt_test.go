@@ -0,0 +1,180 @@
+package tracing_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+const encryptionKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func TestEncryptedFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secret.log")
+	config := NewTestConfig(filename)
+	config.EncryptFile = true
+	config.EncryptionKey = encryptionKey
+	logger := tracing.NewLogger(config)
+
+	ctx := tracing.NewTraceCtx("t1")
+	logger.Info(ctx, "user alice@example.com logged in")
+	logger.Warn(ctx, "second entry")
+
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("alice@example.com")) {
+		t.Fatal("plaintext in the encrypted file")
+	}
+
+	key, _ := tracing.ParseEncryptionKey(encryptionKey)
+	lines := decryptLines(t, bytes.NewReader(raw), key)
+	if len(lines) != 2 || !strings.Contains(lines[0], "alice@example.com") || !strings.Contains(lines[1], "second entry") {
+		t.Errorf("unexpected plaintext %q", lines)
+	}
+
+	// a compressed rotated file
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(raw)
+	zw.Close()
+	if lines := decryptLines(t, &gz, key); len(lines) != 2 {
+		t.Errorf("unexpected plaintext of the compressed file %q", lines)
+	}
+
+	// a modified byte fails the authentication
+	raw[len(raw)-1] ^= 0xff
+	r, _ := tracing.NewDecryptReader(bytes.NewReader(raw), key)
+	if _, err := io.ReadAll(r); err == nil {
+		t.Error("modified frame is not detected")
+	}
+}
+
+func TestEncryptedFileEnvKey(t *testing.T) {
+	t.Setenv(tracing.EnvEncryptionKey, "AAECAwQFBgcICQoLDA0ODw==")
+	filename := filepath.Join(t.TempDir(), "secret.log")
+	logger := tracing.NewLogger(tracing.Config{AppName: "logx_test"},
+		tracing.WithSinks(tracing.SinkConfig{Filename: filename, Encrypt: true}))
+	logger.Info(tracing.NewTraceCtx("t1"), "hello")
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	key, err := tracing.ParseEncryptionKey(os.Getenv(tracing.EnvEncryptionKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := decryptLines(t, f, key)
+	entry := map[string]interface{}{}
+	if len(lines) != 1 || json.Unmarshal([]byte(lines[0]), &entry) != nil || entry["M"] != "hello" {
+		t.Errorf("unexpected plaintext %q", lines)
+	}
+}
+
+// splitFrames splits an encrypted file into its frames.
+func splitFrames(raw []byte) [][]byte {
+	var frames [][]byte
+	for len(raw) >= 4 {
+		n := 4 + int(binary.BigEndian.Uint32(raw))
+		frames = append(frames, raw[:n])
+		raw = raw[n:]
+	}
+	return frames
+}
+
+func TestEncryptedFileFrames(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secret.log")
+	config := NewTestConfig(filename)
+	config.EncryptFile = true
+	config.EncryptionKey = encryptionKey
+	ctx := tracing.NewTraceCtx("t1")
+	for _, messages := range [][]string{{"a", "b", "c"}, {"d", "e"}} {
+		// a restarted process appends a new segment
+		logger := tracing.NewLogger(config)
+		for _, m := range messages {
+			logger.Info(ctx, m)
+		}
+		logger.Close()
+	}
+
+	raw, _ := os.ReadFile(filename)
+	f := splitFrames(raw)
+	key, _ := tracing.ParseEncryptionKey(encryptionKey)
+	if lines := decryptLines(t, bytes.NewReader(raw), key); len(lines) != 5 {
+		t.Fatalf("unexpected plaintext %q", lines)
+	}
+	// a rotated file begins in the middle of a segment
+	if lines := decryptLines(t, bytes.NewReader(bytes.Join(f[1:], nil)), key); len(lines) != 4 {
+		t.Errorf("unexpected plaintext of a rotated file %q", lines)
+	}
+
+	for name, frames := range map[string][][]byte{
+		"deleted":   {f[0], f[2], f[3], f[4]},
+		"reordered": {f[0], f[2], f[1], f[3], f[4]},
+		"repeated":  {f[0], f[1], f[1], f[2], f[3], f[4]},
+		"segment":   {f[0], f[1], f[2], f[4]},
+		"truncated": {f[0], f[1], f[2][:len(f[2])-1]},
+	} {
+		r, _ := tracing.NewDecryptReader(bytes.NewReader(bytes.Join(frames, nil)), key)
+		if _, err := io.ReadAll(r); err == nil {
+			t.Errorf("%s: frames are not rejected", name)
+		}
+	}
+
+	// the segment ID and sequence number are authenticated
+	modified := bytes.Join(f, nil)
+	modified[len(f[0])+len(f[1])+4+16+7] ^= 0x01
+	r, _ := tracing.NewDecryptReader(bytes.NewReader(modified), key)
+	if _, err := io.ReadAll(r); err == nil {
+		t.Error("modified sequence number is not detected")
+	}
+}
+
+func TestEncryptedFileWriteError(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secret.log")
+	config := NewTestConfig(filename)
+	config.EncryptFile = true
+	config.EncryptionKey = encryptionKey
+	config.MaxSize = 1
+	logger := tracing.NewLogger(config)
+
+	ctx := tracing.NewTraceCtx("t1")
+	logger.Info(ctx, "before")
+	// lumberjack fails a write over MaxSize
+	logger.Info(ctx, strings.Repeat("x", 2<<20))
+	logger.Info(ctx, "after")
+	logger.Close()
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	key, _ := tracing.ParseEncryptionKey(encryptionKey)
+	if lines := decryptLines(t, f, key); len(lines) != 2 || !strings.Contains(lines[1], "after") {
+		t.Errorf("unexpected plaintext %q", lines)
+	}
+}
+
+func decryptLines(t *testing.T, r io.Reader, key []byte) []string {
+	t.Helper()
+	dr, err := tracing.NewDecryptReader(r, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
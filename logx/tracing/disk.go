@@ -0,0 +1,279 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	megabyte = 1024 * 1024
+
+	// defaultDiskCheckInterval is the minimum time between two checks of the
+	// free space and the total size of a file sink.
+	defaultDiskCheckInterval = 10 * time.Second
+)
+
+// SinkStatus is the status of a file sink.
+type SinkStatus struct {
+	Filename string `json:"filename"`
+
+	// Degraded determines if the sink only writes the entries at or above
+	// SinkConfig.DegradedLevel, Reason is why and Since is when it began.
+	Degraded bool      `json:"degraded"`
+	Reason   string    `json:"reason,omitempty"`
+	Since    time.Time `json:"since"`
+
+	// FreeSpace is the free space in bytes of the volume of the file, and
+	// TotalSize is the size in bytes of the file and its backups, at the
+	// time of the last check.
+	FreeSpace uint64    `json:"freespace"`
+	TotalSize int64     `json:"totalsize"`
+	CheckedAt time.Time `json:"checkedat"`
+
+	// Dropped is the number of entries dropped while degraded.
+	Dropped uint64 `json:"dropped"`
+
+	// WriteErrors is the number of failed writes, LastError is the last one.
+	WriteErrors   uint64    `json:"writeerrors"`
+	LastError     string    `json:"lasterror,omitempty"`
+	LastErrorTime time.Time `json:"lasterrortime"`
+}
+
+var (
+	_guardsMu sync.Mutex
+	_guards   = map[string]*diskGuard{}
+)
+
+// FileSinkStatuses returns the status of the guarded file sinks of all the
+// loggers which are not closed, sorted by filename.
+func FileSinkStatuses() []SinkStatus {
+	_guardsMu.Lock()
+	guards := make([]*diskGuard, 0, len(_guards))
+	for _, g := range _guards {
+		guards = append(guards, g)
+	}
+	_guardsMu.Unlock()
+
+	statuses := make([]SinkStatus, 0, len(guards))
+	for _, g := range guards {
+		statuses = append(statuses, g.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Filename < statuses[j].Filename })
+	return statuses
+}
+
+// diskGuard watches the writes, the free space and the total size of a file
+// sink, and degrades the sink when the disk is full or about to be.
+type diskGuard struct {
+	filename string
+	minFree  uint64
+	maxTotal int64
+	level    zapcore.Level
+	interval time.Duration
+
+	degraded    int32
+	checked     int64
+	failed      int32
+	dropped     uint64
+	writeErrors uint64
+
+	mu        sync.Mutex
+	reason    string
+	since     time.Time
+	free      uint64
+	total     int64
+	checkedAt time.Time
+	lastErr   string
+	lastErrAt time.Time
+}
+
+// newDiskGuard returns the guard of a file sink, which replaces the status
+// of a previous sink of the same file.
+func newDiskGuard(sink SinkConfig) *diskGuard {
+	filename := sink.Filename
+	if filename == "" {
+		// the default of lumberjack
+		filename = filepath.Join(os.TempDir(), filepath.Base(os.Args[0])+"-lumberjack.log")
+	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	level := zapcore.ErrorLevel
+	if sink.DegradedLevel != "" {
+		level = parseLevel(sink.DegradedLevel)
+	}
+	interval := defaultDiskCheckInterval
+	if sink.DiskCheckInterval > 0 {
+		interval = time.Duration(sink.DiskCheckInterval) * time.Millisecond
+	}
+	g := &diskGuard{
+		filename: filename,
+		minFree:  uint64(sink.MinFreeSpace) * megabyte,
+		maxTotal: int64(sink.MaxTotalSize) * megabyte,
+		level:    level,
+		interval: interval,
+	}
+
+	_guardsMu.Lock()
+	_guards[filename] = g
+	_guardsMu.Unlock()
+	return g
+}
+
+// unregister removes the status of the guard, unless it is replaced by the
+// guard of a later sink of the same file.
+func (g *diskGuard) unregister() {
+	_guardsMu.Lock()
+	if _guards[g.filename] == g {
+		delete(_guards, g.filename)
+	}
+	_guardsMu.Unlock()
+}
+
+// allow returns true if an entry of the level is written, it checks the
+// disk when its interval has passed since the last check.
+func (g *diskGuard) allow(level zapcore.Level) bool {
+	now := time.Now()
+	if now.UnixNano()-atomic.LoadInt64(&g.checked) >= int64(g.interval) && g.mu.TryLock() {
+		g.check(now)
+		g.mu.Unlock()
+	}
+	return level >= g.level || atomic.LoadInt32(&g.degraded) == 0
+}
+
+// wrote records the result of a write, a failed write degrades the sink
+// until a check finds the free space and the total size within the limits.
+func (g *diskGuard) wrote(err error) {
+	if err == nil {
+		atomic.StoreInt32(&g.failed, 0)
+		return
+	}
+	atomic.AddUint64(&g.writeErrors, 1)
+	atomic.StoreInt32(&g.failed, 1)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lastErr = err.Error()
+	g.lastErrAt = time.Now()
+	g.setDegraded(g.lastErrAt, "write error: "+g.lastErr)
+}
+
+// check removes the oldest backups over the total size, and degrades the
+// sink if it is still over the total size or the free space is under the
+// minimum. Otherwise a failed write is forgotten, so the next entry at any
+// level tries the file again and degrades the sink if it still fails. It
+// must be called with mu held.
+func (g *diskGuard) check(now time.Time) {
+	atomic.StoreInt64(&g.checked, now.UnixNano())
+	g.checkedAt = now
+
+	var reasons []string
+	if free, err := diskFree(filepath.Dir(g.filename)); err == nil {
+		g.free = free
+		if free < g.minFree {
+			reasons = append(reasons, fmt.Sprintf("free space %d MB is under %d MB", free/megabyte, g.minFree/megabyte))
+		}
+	}
+
+	g.total = g.removeBackups()
+	if g.maxTotal > 0 && g.total > g.maxTotal {
+		reasons = append(reasons, fmt.Sprintf("total size %d MB is over %d MB", g.total/megabyte, g.maxTotal/megabyte))
+	}
+	if len(reasons) == 0 {
+		atomic.StoreInt32(&g.failed, 0)
+	} else if atomic.LoadInt32(&g.failed) != 0 {
+		reasons = append(reasons, "write error: "+g.lastErr)
+	}
+
+	if len(reasons) > 0 {
+		g.setDegraded(now, strings.Join(reasons, ", "))
+	} else if atomic.CompareAndSwapInt32(&g.degraded, 1, 0) {
+		fmt.Fprintf(os.Stderr, "logx: file sink %s recovered after %s, %d entries dropped\n",
+			g.filename, now.Sub(g.since).Truncate(time.Second), atomic.LoadUint64(&g.dropped))
+		g.reason = ""
+	}
+}
+
+// setDegraded degrades the sink, it must be called with mu held.
+func (g *diskGuard) setDegraded(now time.Time, reason string) {
+	if atomic.CompareAndSwapInt32(&g.degraded, 0, 1) {
+		g.since = now
+		fmt.Fprintf(os.Stderr, "logx: file sink %s degraded to %s: %s\n", g.filename, g.level.CapitalString(), reason)
+	}
+	g.reason = reason
+}
+
+// removeBackups removes the oldest backups until the total size of the file
+// and its backups is under maxTotal, and returns the total size.
+func (g *diskGuard) removeBackups() int64 {
+	var total int64
+	if fi, err := os.Stat(g.filename); err == nil {
+		total = fi.Size()
+	}
+	backups, _ := BackupFiles(g.filename)
+	sizes := make([]int64, len(backups))
+	for i, name := range backups {
+		if fi, err := os.Stat(name); err == nil {
+			sizes[i] = fi.Size()
+			total += sizes[i]
+		}
+	}
+	for i := 0; g.maxTotal > 0 && total > g.maxTotal && i < len(backups); i++ {
+		// lumberjack may have compressed or removed it meanwhile
+		if err := os.Remove(backups[i]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "logx: remove backup: %v\n", err)
+			continue
+		}
+		total -= sizes[i]
+	}
+	return total
+}
+
+func (g *diskGuard) status() SinkStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return SinkStatus{
+		Filename:      g.filename,
+		Degraded:      atomic.LoadInt32(&g.degraded) != 0,
+		Reason:        g.reason,
+		Since:         g.since,
+		FreeSpace:     g.free,
+		TotalSize:     g.total,
+		CheckedAt:     g.checkedAt,
+		Dropped:       atomic.LoadUint64(&g.dropped),
+		WriteErrors:   atomic.LoadUint64(&g.writeErrors),
+		LastError:     g.lastErr,
+		LastErrorTime: g.lastErrAt,
+	}
+}
+
+// guardCore drops the entries under the degraded level of its guard. It is
+// enabled at the levels of the sink, so the dropped entries are counted.
+type guardCore struct {
+	zapcore.Core
+	guard *diskGuard
+}
+
+func (c *guardCore) With(fields []zapcore.Field) zapcore.Core {
+	return &guardCore{Core: c.Core.With(fields), guard: c.guard}
+}
+
+func (c *guardCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Core.Enabled(ent.Level) {
+		return ce
+	}
+	if !c.guard.allow(ent.Level) {
+		atomic.AddUint64(&c.guard.dropped, 1)
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
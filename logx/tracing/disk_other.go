@@ -0,0 +1,10 @@
+//go:build !unix
+
+package tracing
+
+import "errors"
+
+// diskFree is not supported, the free space is not checked.
+func diskFree(dir string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
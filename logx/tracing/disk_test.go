@@ -0,0 +1,162 @@
+package tracing_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+func sinkStatus(t *testing.T, filename string) tracing.SinkStatus {
+	t.Helper()
+	for _, s := range tracing.FileSinkStatuses() {
+		if s.Filename == filename {
+			return s
+		}
+	}
+	t.Fatalf("no status of %s", filename)
+	return tracing.SinkStatus{}
+}
+
+func TestDiskGuardMaxTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	backups := []string{"app-2024-05-01T10-00-00.000.log.gz", "app-2024-05-02T10-00-00.000.log", "app-2024-05-03T10-00-00.000.log"}
+	for _, name := range append(backups, "other.log") {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, 1024*1024), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logger := tracing.NewLogger(tracing.Config{AppName: "logx_test"},
+		tracing.WithSinks(tracing.SinkConfig{Type: "file", Filename: filename, MaxTotalSize: 2}))
+	logger.Info(tracing.NewTraceCtx("t1"), "info message")
+	logger.Sync()
+
+	for i, name := range append(backups, "other.log") {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if removed := os.IsNotExist(err); removed != (i == 0) {
+			t.Errorf("%s removed %v", name, removed)
+		}
+	}
+	s := sinkStatus(t, filename)
+	if s.Degraded || s.TotalSize != 2*1024*1024 || s.CheckedAt.IsZero() {
+		t.Errorf("unexpected status %+v", s)
+	}
+}
+
+func TestDiskGuardDegraded(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	config := NewTestConfig(filename)
+	logger := tracing.NewLogger(config, tracing.WithDiskGuard(1<<30, 0))
+
+	ctx := tracing.NewTraceCtx("t1")
+	logger.Info(ctx, "info message")
+	logger.Error(ctx, "error message")
+	logger.Sync()
+
+	b, _ := os.ReadFile(filename)
+	if strings.Contains(string(b), "info message") || !strings.Contains(string(b), "error message") {
+		t.Errorf("unexpected file %q", b)
+	}
+	s := sinkStatus(t, filename)
+	if !s.Degraded || s.Dropped != 1 || !strings.Contains(s.Reason, "free space") || s.FreeSpace == 0 {
+		t.Errorf("unexpected status %+v", s)
+	}
+
+	rec := httptest.NewRecorder()
+	tracing.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	var status tracing.Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || rec.Code != http.StatusServiceUnavailable || !status.Degraded() {
+		t.Errorf("unexpected response %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestDiskGuardWriteError(t *testing.T) {
+	dir := t.TempDir()
+	// the directory of the file is a file
+	if err := os.WriteFile(filepath.Join(dir, "logs"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "logs", "app.log")
+	logger := tracing.NewLogger(tracing.Config{AppName: "logx_test"},
+		tracing.WithSinks(tracing.SinkConfig{Type: "file", Filename: filename, DegradedLevel: "warn"}))
+
+	ctx := tracing.NewTraceCtx("t1")
+	logger.Warn(ctx, "warn message")
+	logger.Info(ctx, "info message")
+	logger.Warn(ctx, "warn message")
+
+	s := sinkStatus(t, filename)
+	if !s.Degraded || s.WriteErrors != 2 || s.Dropped != 1 || s.LastError == "" || !strings.HasPrefix(s.Reason, "write error") {
+		t.Errorf("unexpected status %+v", s)
+	}
+}
+
+func TestDiskGuardWriteErrorRecovers(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "logs"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "logs", "app.log")
+	logger := tracing.NewLogger(tracing.Config{AppName: "logx_test"},
+		tracing.WithSinks(tracing.SinkConfig{Type: "file", Filename: filename, DegradedLevel: "error", DiskCheckInterval: 10}))
+
+	ctx := tracing.NewTraceCtx("t1")
+	logger.Error(ctx, "error message")
+	if s := sinkStatus(t, filename); !s.Degraded {
+		t.Fatalf("unexpected status %+v", s)
+	}
+
+	// the space is freed, and only entries under the degraded level follow
+	os.Remove(filepath.Join(dir, "logs"))
+	time.Sleep(20 * time.Millisecond)
+	logger.Info(ctx, "info message")
+	logger.Sync()
+
+	if b, _ := os.ReadFile(filename); !strings.Contains(string(b), "info message") {
+		t.Errorf("unexpected file %q", b)
+	}
+	if s := sinkStatus(t, filename); s.Degraded || s.WriteErrors != 1 || s.Dropped != 0 {
+		t.Errorf("unexpected status %+v", s)
+	}
+}
+
+func TestDiskGuardOptIn(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.log")
+	guarded := filepath.Join(dir, "guarded.log")
+	logger := tracing.NewLogger(tracing.Config{AppName: "logx_test"},
+		tracing.WithSinks(
+			tracing.SinkConfig{Type: "file", Filename: plain},
+			tracing.SinkConfig{Type: "file", Filename: guarded, DegradedLevel: "warn"}))
+	logger.Info(tracing.NewTraceCtx("t1"), "info message")
+
+	filenames := func() []string {
+		var names []string
+		for _, s := range tracing.FileSinkStatuses() {
+			if filepath.Dir(s.Filename) == dir {
+				names = append(names, s.Filename)
+			}
+		}
+		return names
+	}
+	if names := filenames(); len(names) != 1 || names[0] != guarded {
+		t.Errorf("unexpected statuses of %v", names)
+	}
+	if b, _ := os.ReadFile(plain); !strings.Contains(string(b), "info message") {
+		t.Errorf("unexpected file %q", b)
+	}
+
+	logger.Close()
+	if names := filenames(); len(names) != 0 {
+		t.Errorf("statuses of %v after Close", names)
+	}
+}
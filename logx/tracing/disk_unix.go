@@ -0,0 +1,15 @@
+//go:build unix
+
+package tracing
+
+import "syscall"
+
+// diskFree returns the space in bytes available to the process on the
+// volume of the directory.
+func diskFree(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,211 @@
+package tracing
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const ecsVersion = "8.11.0"
+
+// ecsFieldNames maps our fields onto the Elastic Common Schema, see
+// https://www.elastic.co/guide/en/ecs/current/ecs-field-reference.html
+var ecsFieldNames = map[string]string{
+	"LAPP":           "service.name",
+	"LPID":           "process.pid",
+	"LIP":            "host.ip",
+	"TRACE_ID":       "trace.id",
+	"SPAN_ID":        "span.id",
+	"PARENT_SPAN_ID": "parent.id",
+	"REQUEST_ID":     "http.request.id",
+	"LHOST":          "host.hostname",
+	"LCONTAINER":     "container.id",
+	"LPOD":           "kubernetes.pod.name",
+	"LNAMESPACE":     "kubernetes.namespace",
+	"LNODE":          "kubernetes.node.name",
+}
+
+// ecsHTTPFieldNames maps the fields of the httpRequest object, see
+// logx.HTTPPayload.
+var ecsHTTPFieldNames = map[string]string{
+	"requestMethod": "http.request.method",
+	"requestUrl":    "url.original",
+	"requestSize":   "http.request.bytes",
+	"status":        "http.response.status_code",
+	"responseSize":  "http.response.bytes",
+	"userAgent":     "user_agent.original",
+	"remoteIp":      "client.address",
+	"serverIp":      "server.address",
+	"referer":       "http.request.referrer",
+	"protocol":      "http.version",
+	"latency":       "event.duration",
+}
+
+// ecsFields returns the fields of the entry with ECS names, except the
+// timestamp, level and message. Fields without an ECS name keep their key.
+func ecsFields(e mapEncoder, ent zapcore.Entry, fields []zapcore.Field) map[string]interface{} {
+	doc := map[string]interface{}{}
+	if ent.LoggerName != "" {
+		doc["log.logger"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		caller := e.caller(ent.Caller)
+		if i := strings.LastIndexByte(caller, ':'); i > 0 {
+			if line, err := strconv.Atoi(caller[i+1:]); err == nil {
+				doc["log.origin.file.line"] = line
+				caller = caller[:i]
+			}
+		}
+		doc["log.origin.file.name"] = caller
+		if ent.Caller.Function != "" && e.cfg.FunctionKey != zapcore.OmitKey {
+			doc["log.origin.function"] = ent.Caller.Function
+		}
+	}
+
+	for _, f := range e.fields(fields) {
+		if name, ok := ecsFieldNames[f.key]; ok {
+			if s, ok := f.value.(string); !ok || s != "" {
+				doc[name] = f.value
+			}
+			continue
+		}
+		switch f.key {
+		case "error":
+			ecsError(doc, f.value)
+		case "errorVerbose":
+			doc["error.stack_trace"] = f.value
+		case "httpRequest":
+			ecsHTTP(doc, f.value)
+		default:
+			doc[f.key] = e.value(f.value)
+		}
+	}
+
+	if _, ok := doc["error.stack_trace"]; !ok && ent.Stack != "" {
+		doc["error.stack_trace"] = ent.Stack
+	}
+	return doc
+}
+
+// ecsError maps the error object of ErrorField, or the error message of
+// zap.Error.
+func ecsError(doc map[string]interface{}, v interface{}) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		doc["error.message"] = v
+		return
+	}
+	for k, v := range obj {
+		switch k {
+		case "type":
+			doc["error.type"] = v
+		case "msg":
+			doc["error.message"] = v
+		case "stack":
+			doc["error.stack_trace"] = v
+		default:
+			doc["error."+k] = v
+		}
+	}
+}
+
+// ecsHTTP maps the httpRequest object, empty values are dropped and the
+// fields without an ECS name are kept in httpRequest.
+func ecsHTTP(doc map[string]interface{}, v interface{}) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		doc["httpRequest"] = v
+		return
+	}
+	rest := map[string]interface{}{}
+	for k, v := range obj {
+		switch v {
+		case "", 0, false:
+			continue
+		}
+		name, ok := ecsHTTPFieldNames[k]
+		if !ok {
+			rest[k] = v
+			continue
+		}
+		switch k {
+		case "requestSize", "responseSize":
+			if n, err := strconv.ParseInt(textValue(v), 10, 64); err == nil {
+				v = n
+			}
+		case "protocol":
+			v = strings.TrimPrefix(textValue(v), "HTTP/")
+		case "latency":
+			d, err := time.ParseDuration(textValue(v))
+			if err != nil {
+				rest[k] = v
+				continue
+			}
+			v = d.Nanoseconds()
+		}
+		doc[name] = v
+	}
+	if len(rest) > 0 {
+		doc["httpRequest"] = rest
+	}
+}
+
+// ecsEncoder encodes entries as ECS JSON documents.
+type ecsEncoder struct {
+	mapEncoder
+}
+
+func newECSEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &ecsEncoder{newMapEncoder(cfg)}
+}
+
+func (e *ecsEncoder) Clone() zapcore.Encoder {
+	return &ecsEncoder{e.clone()}
+}
+
+func (e *ecsEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	doc := ecsFields(e.mapEncoder, ent, fields)
+	doc["ecs.version"] = ecsVersion
+
+	buf := _bufferPool.Get()
+	// @timestamp, log.level and message come first, as in the ECS loggers.
+	buf.AppendString(`{"@timestamp":`)
+	appendJSON(buf, ent.Time.UTC().Format("2006-01-02T15:04:05.000Z"))
+	buf.AppendString(`,"log.level":`)
+	appendJSON(buf, ent.Level.String())
+	buf.AppendString(`,"message":`)
+	appendJSON(buf, ent.Message)
+
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf.AppendByte(',')
+		appendJSON(buf, k)
+		buf.AppendByte(':')
+		appendJSON(buf, doc[k])
+	}
+	buf.AppendByte('}')
+	buf.AppendString(e.lineEnding())
+	return buf, nil
+}
+
+// appendJSON appends v as JSON without escaping HTML, a value which can not
+// be marshaled is appended as a string.
+func appendJSON(buf *buffer.Buffer, v interface{}) {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		b.Reset()
+		enc.Encode(textValue(v))
+	}
+	buf.AppendString(strings.TrimSuffix(b.String(), "\n"))
+}
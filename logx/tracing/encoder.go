@@ -0,0 +1,291 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var _bufferPool = buffer.NewPool()
+
+// isTerminal returns true if f is a character device, e.g. a TTY. Colors are
+// also disabled by the NO_COLOR environment variable.
+func isTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// newEncoderConfig returns the encoder config of the keys, time format and
+// duration encoding of the config.
+func newEncoderConfig(config Config) zapcore.EncoderConfig {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = encoderKey(config.TimeKey, "T")
+	encoderConfig.LevelKey = encoderKey(config.LevelKey, "L")
+	encoderConfig.MessageKey = encoderKey(config.MessageKey, "M")
+	encoderConfig.CallerKey = encoderKey(config.CallerKey, "LFILE")
+	encoderConfig.NameKey = encoderKey(config.NameKey, "logger")
+	encoderConfig.StacktraceKey = encoderKey(config.StacktraceKey, "stacktrace")
+	encoderConfig.FunctionKey = encoderKey(config.FunctionKey, zapcore.OmitKey)
+	encoderConfig.EncodeTime = newTimeEncoder(config.TimeFormat, config.TimeZone)
+	encoderConfig.EncodeDuration = newDurationEncoder(config.DurationEncoding)
+	if config.Golden {
+		encoderConfig.EncodeCaller = goldenCallerEncoder
+	}
+	return encoderConfig
+}
+
+// encoderKey returns the key, "-" omits the key and def is used if empty.
+func encoderKey(key string, def string) string {
+	switch key {
+	case "":
+		return def
+	case "-":
+		return zapcore.OmitKey
+	default:
+		return key
+	}
+}
+
+// newTimeEncoder returns the time encoder of the format, see
+// Config.TimeFormat. An unknown time zone is ignored.
+func newTimeEncoder(format string, zone string) zapcore.TimeEncoder {
+	var enc zapcore.TimeEncoder
+	switch strings.ToLower(format) {
+	case "", "iso8601":
+		enc = zapcore.ISO8601TimeEncoder
+	case "rfc3339":
+		enc = zapcore.RFC3339TimeEncoder
+	case "rfc3339nano":
+		enc = zapcore.RFC3339NanoTimeEncoder
+	case "epoch":
+		enc = zapcore.EpochTimeEncoder
+	case "epochmillis":
+		enc = zapcore.EpochMillisTimeEncoder
+	case "epochnanos":
+		enc = zapcore.EpochNanosTimeEncoder
+	default:
+		enc = zapcore.TimeEncoderOfLayout(format)
+	}
+
+	if zone == "" {
+		return enc
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return enc
+	}
+	return func(t time.Time, pae zapcore.PrimitiveArrayEncoder) {
+		enc(t.In(loc), pae)
+	}
+}
+
+// newDurationEncoder returns the duration encoder of the encoding, see
+// Config.DurationEncoding.
+func newDurationEncoder(encoding string) zapcore.DurationEncoder {
+	switch strings.ToLower(encoding) {
+	case "millis", "ms":
+		return zapcore.MillisDurationEncoder
+	case "seconds", "secs":
+		return zapcore.SecondsDurationEncoder
+	case "string":
+		return zapcore.StringDurationEncoder
+	default:
+		return zapcore.NanosDurationEncoder
+	}
+}
+
+// field is a field converted by zapcore.MapObjectEncoder.
+type field struct {
+	key   string
+	value interface{}
+}
+
+// encodeFields converts fields in order, fields which add nothing, such as
+// zap.Skip, are dropped.
+func encodeFields(fields []zapcore.Field) []field {
+	out := make([]field, 0, len(fields))
+	for _, f := range fields {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		for k, v := range enc.Fields {
+			out = append(out, field{k, v})
+		}
+	}
+	return out
+}
+
+// mapEncoder is the base of the encoders which are not built on the zap
+// JSON encoder, the logger fields are kept by a zapcore.MapObjectEncoder.
+type mapEncoder struct {
+	*zapcore.MapObjectEncoder
+	cfg zapcore.EncoderConfig
+}
+
+func newMapEncoder(cfg zapcore.EncoderConfig) mapEncoder {
+	return mapEncoder{zapcore.NewMapObjectEncoder(), cfg}
+}
+
+func (e mapEncoder) clone() mapEncoder {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range e.Fields {
+		enc.Fields[k] = v
+	}
+	return mapEncoder{enc, e.cfg}
+}
+
+// fields returns the logger fields sorted by key, followed by the fields of
+// the entry in order.
+func (e mapEncoder) fields(fields []zapcore.Field) []field {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	all := make([]field, 0, len(keys)+len(fields))
+	for _, k := range keys {
+		all = append(all, field{k, e.Fields[k]})
+	}
+	return append(all, encodeFields(fields)...)
+}
+
+// value converts times and durations with the encoder config.
+func (e mapEncoder) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		if e.cfg.EncodeTime != nil {
+			var p primitiveValues
+			e.cfg.EncodeTime(v, &p)
+			return p.value()
+		}
+	case time.Duration:
+		if e.cfg.EncodeDuration != nil {
+			var p primitiveValues
+			e.cfg.EncodeDuration(v, &p)
+			return p.value()
+		}
+	}
+	return v
+}
+
+// caller returns the caller encoded with the encoder config.
+func (e mapEncoder) caller(c zapcore.EntryCaller) string {
+	if e.cfg.EncodeCaller == nil {
+		return c.TrimmedPath()
+	}
+	var p primitiveValues
+	e.cfg.EncodeCaller(c, &p)
+	return p.String()
+}
+
+func (e mapEncoder) lineEnding() string {
+	if e.cfg.LineEnding != "" {
+		return e.cfg.LineEnding
+	}
+	return zapcore.DefaultLineEnding
+}
+
+// flatten calls fn for each leaf of nested objects, the keys are joined by
+// sep. Arrays are leaves.
+func flatten(key string, v interface{}, sep string, fn func(string, interface{})) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		fn(key, v)
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		flatten(key+sep+k, m[k], sep, fn)
+	}
+}
+
+// textValue formats a value as text, objects and arrays are formatted as
+// JSON.
+func textValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64, complex64, complex128:
+		return fmt.Sprint(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// quoteText quotes s if it is empty or contains spaces, quotes, '=' or
+// control characters.
+func quoteText(s string) string {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == 0x7f
+	}) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// primitiveValues collects the values appended by the Encode* functions of
+// a zapcore.EncoderConfig.
+type primitiveValues []interface{}
+
+func (p *primitiveValues) value() interface{} {
+	switch len(*p) {
+	case 0:
+		return ""
+	case 1:
+		return (*p)[0]
+	default:
+		return []interface{}(*p)
+	}
+}
+
+func (p *primitiveValues) String() string {
+	return textValue(p.value())
+}
+
+func (p *primitiveValues) add(v interface{})             { *p = append(*p, v) }
+func (p *primitiveValues) AppendBool(v bool)             { p.add(v) }
+func (p *primitiveValues) AppendByteString(v []byte)     { p.add(string(v)) }
+func (p *primitiveValues) AppendComplex128(v complex128) { p.add(fmt.Sprint(v)) }
+func (p *primitiveValues) AppendComplex64(v complex64)   { p.add(fmt.Sprint(v)) }
+func (p *primitiveValues) AppendFloat64(v float64)       { p.add(v) }
+func (p *primitiveValues) AppendFloat32(v float32)       { p.add(v) }
+func (p *primitiveValues) AppendInt(v int)               { p.add(v) }
+func (p *primitiveValues) AppendInt64(v int64)           { p.add(v) }
+func (p *primitiveValues) AppendInt32(v int32)           { p.add(v) }
+func (p *primitiveValues) AppendInt16(v int16)           { p.add(v) }
+func (p *primitiveValues) AppendInt8(v int8)             { p.add(v) }
+func (p *primitiveValues) AppendString(v string)         { p.add(v) }
+func (p *primitiveValues) AppendUint(v uint)             { p.add(v) }
+func (p *primitiveValues) AppendUint64(v uint64)         { p.add(v) }
+func (p *primitiveValues) AppendUint32(v uint32)         { p.add(v) }
+func (p *primitiveValues) AppendUint16(v uint16)         { p.add(v) }
+func (p *primitiveValues) AppendUint8(v uint8)           { p.add(v) }
+func (p *primitiveValues) AppendUintptr(v uintptr)       { p.add(v) }
@@ -0,0 +1,191 @@
+package tracing_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// httpRequest is a minimal logx.HTTPPayload.
+var httpRequest = zap.Object("httpRequest", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+	enc.AddString("requestMethod", "GET")
+	enc.AddString("requestUrl", "/orders?id=1")
+	enc.AddString("responseSize", "512")
+	enc.AddInt("status", 200)
+	enc.AddString("userAgent", "curl/8.0")
+	enc.AddString("latency", "1.5s")
+	enc.AddString("protocol", "HTTP/1.1")
+	enc.AddString("referer", "")
+	enc.AddInt("retCode", 7)
+	return nil
+}))
+
+func logHTTP(t *testing.T, encoding string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), encoding+".log")
+	config := NewTestConfig(filename)
+	config.FileEncodeing = encoding
+	logger := tracing.NewLogger(config)
+
+	ctx, span := logger.StartSpan(tracing.NewTraceCtx(""), "GET /orders")
+	logger.WithField(httpRequest).Info(ctx, "request done")
+	span.End()
+	return filename
+}
+
+func TestECSEncoding(t *testing.T) {
+	entries := readEntries(t, logHTTP(t, "ecs"))
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	e := entries[0]
+	for key, want := range map[string]interface{}{
+		"log.level":                 "info",
+		"message":                   "request done",
+		"service.name":              "logx_test",
+		"http.request.method":       "GET",
+		"url.original":              "/orders?id=1",
+		"http.response.status_code": float64(200),
+		"http.response.bytes":       float64(512),
+		"user_agent.original":       "curl/8.0",
+		"event.duration":            float64(1500000000),
+		"http.version":              "1.1",
+	} {
+		if e[key] != want {
+			t.Errorf("%s = %v, want %v", key, e[key], want)
+		}
+	}
+	if e["trace.id"] == nil || e["span.id"] == nil || e["@timestamp"] == nil {
+		t.Errorf("missing trace or timestamp in %v", e)
+	}
+	if _, ok := e["http.request.referrer"]; ok {
+		t.Errorf("empty referer is not dropped")
+	}
+	if rest, _ := e["httpRequest"].(map[string]interface{}); rest["retCode"] != float64(7) {
+		t.Errorf("unmapped fields are not kept: %v", e["httpRequest"])
+	}
+	if entries[1]["SPAN_NAME"] != "GET /orders" {
+		t.Errorf("unexpected span summary %v", entries[1])
+	}
+}
+
+func TestGELFEncoding(t *testing.T) {
+	entries := readEntries(t, logHTTP(t, "gelf"))
+	e := entries[0]
+	for key, want := range map[string]interface{}{
+		"version":                    "1.1",
+		"short_message":              "request done",
+		"level":                      float64(6),
+		"_service_name":              "logx_test",
+		"_http_response_status_code": float64(200),
+		"_httpRequest_retCode":       float64(7),
+	} {
+		if e[key] != want {
+			t.Errorf("%s = %v, want %v", key, e[key], want)
+		}
+	}
+	if e["host"] == nil || e["timestamp"] == nil || e["_trace_id"] == nil {
+		t.Errorf("missing host, timestamp or trace in %v", e)
+	}
+	for key := range e {
+		if key != "version" && key != "host" && key != "short_message" && key != "full_message" &&
+			key != "timestamp" && key != "level" && !strings.HasPrefix(key, "_") {
+			t.Errorf("additional field %s is not prefixed", key)
+		}
+	}
+}
+
+func TestLogfmtEncoding(t *testing.T) {
+	b, err := os.ReadFile(logHTTP(t, "logfmt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.SplitN(string(b), "\n", 2)[0]
+	for _, want := range []string{
+		` L=INFO `,
+		` M="request done" `,
+		` httpRequest.status=200 `,
+		` httpRequest.requestUrl="/orders?id=1" `,
+		` httpRequest.referer="" `,
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("%q does not contain %q", line, want)
+		}
+	}
+}
+
+func TestEncoderKeys(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "keys.log")
+	config := NewTestConfig(filename)
+	config.TimeKey = "ts"
+	config.LevelKey = "level"
+	config.MessageKey = "msg"
+	config.CallerKey = "-"
+	config.FunctionKey = "func"
+	config.DurationEncoding = "string"
+	logger := tracing.NewLogger(config, tracing.WithTimeFormat("epochmillis", "UTC"))
+
+	logger.WithField(zap.Duration("elapsed", 1500*time.Millisecond)).Info(tracing.NewTraceCtx("t1"), "hello")
+
+	e := readEntries(t, filename)[0]
+	if e["level"] != "INFO" || e["msg"] != "hello" || e["elapsed"] != "1.5s" {
+		t.Errorf("unexpected entry %v", e)
+	}
+	if ts, ok := e["ts"].(float64); !ok || time.Since(time.UnixMilli(int64(ts))) > time.Minute {
+		t.Errorf("unexpected time %v", e["ts"])
+	}
+	if _, ok := e["LFILE"]; ok {
+		t.Errorf("caller is not omitted: %v", e)
+	}
+	if fn, _ := e["func"].(string); !strings.HasSuffix(fn, "TestEncoderKeys") {
+		t.Errorf("unexpected function %v", e["func"])
+	}
+}
+
+func TestTimeFormatLayout(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "layout.log")
+	logger := tracing.NewLogger(NewTestConfig(filename), tracing.WithGolden(),
+		tracing.WithTimeFormat("2006/01/02 15:04:05", "Asia/Shanghai"))
+
+	logger.Info(tracing.NewTraceCtx("t1"), "hello")
+
+	e := readEntries(t, filename)[0]
+	if e["T"] != "2006/01/02 23:04:05" {
+		t.Errorf("unexpected time %v", e["T"])
+	}
+}
+
+func TestPrettyTimeFormat(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "pretty.log")
+	config := NewTestConfig(filename)
+	config.FileEncodeing = "pretty"
+
+	// the short format in the time zone, and the configured format
+	for _, tt := range []struct {
+		format string
+		want   string
+	}{
+		{"", "23:04:05.000 INFO"},
+		{"rfc3339", "2006-01-02T23:04:05+08:00 INFO"},
+	} {
+		os.Remove(filename)
+		logger := tracing.NewLogger(config, tracing.WithGolden(), tracing.WithTimeFormat(tt.format, "Asia/Shanghai"))
+		logger.Info(tracing.NewTraceCtx("t1"), "hello")
+		logger.Close()
+
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(b), tt.want+" ") {
+			t.Errorf("format %q: got %q, want the prefix %q", tt.format, b, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorField returns a field which records the error type, message, every
+// wrapped cause and the stack trace carried by the error. It returns a no-op
+// field if err is nil.
+func ErrorField(err error) zap.Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	return zap.Object("error", errorObject{err})
+}
+
+type errorObject struct {
+	err error
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaller interface.
+func (e errorObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("type", errorType(e.err))
+	enc.AddString("msg", errorMessage(e.err))
+
+	causes := errorCauses(e.err)
+	if len(causes) > 0 {
+		if err := enc.AddArray("causes", causes); err != nil {
+			return err
+		}
+	}
+
+	if stack := errorStack(e.err); stack != "" {
+		enc.AddString("stack", stack)
+	}
+	return nil
+}
+
+type errorCause struct {
+	err error
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaller interface.
+func (e errorCause) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("type", errorType(e.err))
+	enc.AddString("msg", errorMessage(e.err))
+	return nil
+}
+
+type errorCauseArray []errorCause
+
+// MarshalLogArray implements zapcore.ArrayMarshaler interface.
+func (causes errorCauseArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, cause := range causes {
+		if err := enc.AppendObject(cause); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func errorType(err error) string {
+	return reflect.TypeOf(err).String()
+}
+
+// isNilError reports whether err is a typed nil, such as a nil *MyError, whose
+// methods may panic.
+func isNilError(err error) bool {
+	v := reflect.ValueOf(err)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// errorMessage returns the message of err, "<nil>" for a typed nil.
+func errorMessage(err error) string {
+	if isNilError(err) {
+		return "<nil>"
+	}
+	return err.Error()
+}
+
+// unwrapErrors returns the errors wrapped by err, both errors.Unwrap and
+// errors.Join style wrapping are supported.
+func unwrapErrors(err error) []error {
+	if isNilError(err) {
+		return nil
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		return e.Unwrap()
+	case interface{ Unwrap() error }:
+		if cause := e.Unwrap(); cause != nil {
+			return []error{cause}
+		}
+	}
+	return nil
+}
+
+// errorCauses walks the error tree depth first, err itself is not included.
+func errorCauses(err error) errorCauseArray {
+	var causes errorCauseArray
+	var walk func(error)
+	walk = func(err error) {
+		for _, cause := range unwrapErrors(err) {
+			if cause == nil {
+				continue
+			}
+			causes = append(causes, errorCause{cause})
+			walk(cause)
+		}
+	}
+	walk(err)
+	return causes
+}
+
+// errorStack returns the deepest stack trace carried by the error chain.
+// Errors that implement StackTrace() (github.com/pkg/errors) or
+// Stack() []byte (github.com/go-errors/errors) are supported.
+func errorStack(err error) (stack string) {
+	if isNilError(err) {
+		return ""
+	}
+	for _, cause := range unwrapErrors(err) {
+		if s := errorStack(cause); s != "" {
+			return s
+		}
+	}
+
+	if e, ok := err.(interface{ Stack() []byte }); ok {
+		return string(e.Stack())
+	}
+
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+		return fmt.Sprintf("%+v", m.Call(nil)[0].Interface())
+	}
+	return ""
+}
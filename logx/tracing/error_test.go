@@ -0,0 +1,114 @@
+package tracing_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+type stackError struct {
+	msg string
+}
+
+func (e *stackError) Error() string { return e.msg }
+func (e *stackError) Stack() []byte { return []byte("main.go:42") }
+
+// readEntries returns the json entries written to filename.
+func readEntries(t *testing.T, filename string) []map[string]interface{} {
+	t.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid json entry %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestErr(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "err.log")
+	logger := tracing.NewLogger(NewTestConfig(filename), tracing.WithStacktraceLevel("error"))
+
+	root := &stackError{"connection refused"}
+	err := fmt.Errorf("query failed: %w", errors.Join(root, errors.New("retry exhausted")))
+	logger.Err(tracing.NewTraceCtx("err-trace"), err, "load user failed")
+	logger.ErrAt(tracing.NewTraceCtx("err-trace"), "warn", nil, "no error")
+
+	entries := readEntries(t, filename)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	e := entries[0]
+	if e["L"] != "ERROR" || e["M"] != "load user failed" || e["TRACE_ID"] != "err-trace" {
+		t.Errorf("unexpected entry %v", e)
+	}
+	if _, ok := e["stacktrace"]; !ok {
+		t.Errorf("entry has no stacktrace: %v", e)
+	}
+
+	obj := e["error"].(map[string]interface{})
+	if obj["type"] != "*fmt.wrapError" || obj["msg"] != err.Error() {
+		t.Errorf("unexpected error object %v", obj)
+	}
+	if obj["stack"] != "main.go:42" {
+		t.Errorf("error stack is %v, want main.go:42", obj["stack"])
+	}
+	causes := obj["causes"].([]interface{})
+	if len(causes) != 3 {
+		t.Fatalf("got %d causes, want 3: %v", len(causes), causes)
+	}
+	if c := causes[1].(map[string]interface{}); c["type"] != "*tracing_test.stackError" {
+		t.Errorf("unexpected cause %v", c)
+	}
+
+	if e := entries[1]; e["L"] != "WARN" || e["error"] != nil || e["stacktrace"] != nil {
+		t.Errorf("unexpected entry %v", e)
+	}
+}
+
+// traceError has a github.com/pkg/errors style StackTrace, which panics for
+// a nil *traceError.
+type traceError struct {
+	msg   string
+	stack []string
+}
+
+func (e *traceError) Error() string        { return e.msg }
+func (e *traceError) StackTrace() []string { return e.stack }
+
+func TestErrTypedNil(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "nil.log")
+	logger := tracing.NewLogger(NewTestConfig(filename))
+
+	var typed *traceError
+	logger.Err(tracing.NewTraceCtx("nil-trace"), typed, "typed nil")
+	logger.Err(tracing.NewTraceCtx("nil-trace"), fmt.Errorf("wrapped: %w", typed), "wrapped typed nil")
+
+	entries := readEntries(t, filename)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if obj := entries[0]["error"].(map[string]interface{}); obj["type"] != "*tracing_test.traceError" || obj["msg"] != "<nil>" || obj["stack"] != nil {
+		t.Errorf("unexpected error object %v", obj)
+	}
+	obj := entries[1]["error"].(map[string]interface{})
+	if c := obj["causes"].([]interface{})[0].(map[string]interface{}); c["msg"] != "<nil>" {
+		t.Errorf("unexpected cause %v", c)
+	}
+}
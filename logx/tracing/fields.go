@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	KeyLogFields = "X-Log-Fields"
+
+	// MaxContextFields is the maximum number of fields carried on a context,
+	// further fields are dropped.
+	MaxContextFields = 32
+
+	// MaxContextFieldSize is the maximum length in bytes of a string value
+	// carried on a context, longer values are truncated and marked with
+	// TruncatedMarker.
+	MaxContextFieldSize = 256
+)
+
+const (
+	headerBaggage  = "Baggage"
+	maxBaggageSize = 8192
+)
+
+type contextField struct {
+	key   string
+	value interface{}
+}
+
+// WithFields returns a context carrying the key value pairs, every log call
+// with the context includes them. A key which is already carried is replaced.
+func WithFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	prev := contextFields(ctx)
+	fields := make([]contextField, len(prev), len(prev)+len(keysAndValues)/2)
+	copy(fields, prev)
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		value := keysAndValues[i+1]
+		if s, ok := value.(string); ok && len(s) > MaxContextFieldSize {
+			value = truncateString(s, MaxContextFieldSize) + TruncatedMarker
+		}
+
+		replaced := false
+		for j := range fields {
+			if fields[j].key == key {
+				fields[j].value = value
+				replaced = true
+				break
+			}
+		}
+		if !replaced && len(fields) < MaxContextFields {
+			fields = append(fields, contextField{key, value})
+		}
+	}
+	return context.WithValue(ctx, KeyLogFields, fields)
+}
+
+func contextFields(ctx context.Context) []contextField {
+	// fasthttp 只支持 string 类型 key
+	fields, _ := ctx.Value(KeyLogFields).([]contextField)
+	return fields
+}
+
+// ContextFields returns the fields carried on the context.
+func ContextFields(ctx context.Context) []zap.Field {
+	cf := contextFields(ctx)
+	if len(cf) == 0 {
+		return nil
+	}
+	fields := make([]zap.Field, 0, len(cf))
+	for _, f := range cf {
+		fields = append(fields, zap.Any(f.key, f.value))
+	}
+	return fields
+}
+
+// InjectBaggage adds the selected context fields to the W3C baggage header,
+// all the context fields are added if no keys are given. A member of the
+// header with the key of a field is replaced.
+func InjectBaggage(ctx context.Context, header http.Header, keys ...string) {
+	type member struct {
+		key  string
+		text string
+	}
+	members := []member{}
+	size := 0
+	for _, b := range header.Values(headerBaggage) {
+		for _, text := range strings.Split(b, ",") {
+			text = strings.TrimSpace(text)
+			if text == "" {
+				continue
+			}
+			key, _, _ := parseBaggageMember(text)
+			members = append(members, member{key, text})
+			size += len(text) + 1
+		}
+	}
+
+	for _, f := range contextFields(ctx) {
+		if len(keys) > 0 && !containsString(keys, f.key) {
+			continue
+		}
+		text := url.PathEscape(f.key) + "=" + url.PathEscape(fmt.Sprint(f.value))
+
+		i := 0
+		for i < len(members) && members[i].key != f.key {
+			i++
+		}
+		if i < len(members) {
+			if size-len(members[i].text)+len(text) <= maxBaggageSize {
+				size += len(text) - len(members[i].text)
+				members[i].text = text
+			}
+			continue
+		}
+		if size+len(text)+1 > maxBaggageSize {
+			break
+		}
+		size += len(text) + 1
+		members = append(members, member{f.key, text})
+	}
+
+	if len(members) > 0 {
+		texts := make([]string, len(members))
+		for i, m := range members {
+			texts[i] = m.text
+		}
+		header.Set(headerBaggage, strings.Join(texts, ","))
+	}
+}
+
+// ExtractBaggage returns a context carrying the selected members of the W3C
+// baggage header as fields, all the members are carried if no keys are given.
+func ExtractBaggage(ctx context.Context, header http.Header, keys ...string) context.Context {
+	kvs := []interface{}{}
+	for _, b := range header.Values(headerBaggage) {
+		for _, member := range strings.Split(b, ",") {
+			key, value, ok := parseBaggageMember(member)
+			if !ok || (len(keys) > 0 && !containsString(keys, key)) {
+				continue
+			}
+			kvs = append(kvs, key, value)
+		}
+	}
+	if len(kvs) == 0 {
+		return ctx
+	}
+	return WithFields(ctx, kvs...)
+}
+
+// parseBaggageMember returns the unescaped key and value of a baggage member,
+// the properties after ';' are ignored.
+func parseBaggageMember(member string) (key string, value string, ok bool) {
+	member = strings.TrimSpace(strings.SplitN(member, ";", 2)[0])
+	kv := strings.SplitN(member, "=", 2)
+	if len(kv) != 2 {
+		return "", "", false
+	}
+	key, err := url.PathUnescape(strings.TrimSpace(kv[0]))
+	if err != nil {
+		return "", "", false
+	}
+	value, err = url.PathUnescape(strings.TrimSpace(kv[1]))
+	if err != nil {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+// BaggageTransport is a http.RoundTripper which adds the selected context
+// fields of the request to the W3C baggage header.
+type BaggageTransport struct {
+	// Base is the underlying RoundTripper, http.DefaultTransport if nil.
+	Base http.RoundTripper
+
+	// Keys are the context fields propagated, all the fields if empty.
+	Keys []string
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (t *BaggageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if len(contextFields(req.Context())) == 0 {
+		return base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	InjectBaggage(req.Context(), req.Header, t.Keys...)
+	return base.RoundTrip(req)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+func TestWithFields(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "fields.log")
+	logger := tracing.NewLogger(NewTestConfig(filename))
+
+	ctx := tracing.WithFields(tracing.NewTraceCtx("fields-trace"), "user_id", 100, "tenant", "t1")
+	ctx = tracing.WithFields(ctx, "tenant", "t2", "order_id", "x"+strings.Repeat("é", 500))
+	logger.With("region", "sh").Info(ctx, "with fields")
+	logger.Info(context.Background(), "without fields")
+
+	entries := readEntries(t, filename)
+	e := entries[0]
+	if e["user_id"] != float64(100) || e["tenant"] != "t2" || e["region"] != "sh" || e["TRACE_ID"] != "fields-trace" {
+		t.Errorf("unexpected entry %v", e)
+	}
+	// the last character is not split
+	if orderID := e["order_id"].(string); orderID != "x"+strings.Repeat("é", (tracing.MaxContextFieldSize-1)/2)+tracing.TruncatedMarker {
+		t.Errorf("unexpected order_id %q", orderID)
+	}
+	if _, ok := entries[1]["user_id"]; ok {
+		t.Errorf("unexpected entry %v", entries[1])
+	}
+
+	many := []interface{}{}
+	for i := 0; i < tracing.MaxContextFields+10; i++ {
+		many = append(many, "k"+strings.Repeat("x", i), i)
+	}
+	if n := len(tracing.ContextFields(tracing.WithFields(context.Background(), many...))); n != tracing.MaxContextFields {
+		t.Errorf("got %d context fields, want %d", n, tracing.MaxContextFields)
+	}
+}
+
+func TestBaggage(t *testing.T) {
+	ctx := tracing.WithFields(context.Background(), "user_id", 100, "tenant", "a b,c", "secret", "s")
+
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &tracing.BaggageTransport{Keys: []string{"user_id", "tenant"}}}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if b := got.Get("Baggage"); b != "user_id=100,tenant=a%20b%2Cc" {
+		t.Errorf("unexpected baggage %q", b)
+	}
+
+	fields := tracing.ContextFields(tracing.ExtractBaggage(context.Background(), got, "tenant"))
+	if len(fields) != 1 || fields[0].Key != "tenant" || fields[0].String != "a b,c" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
+
+func TestInjectBaggageReplaces(t *testing.T) {
+	ctx := tracing.WithFields(context.Background(), "user_id", 200, "tenant", "b")
+	header := http.Header{}
+	header.Add("Baggage", "user_id=100;prop=1, region=eu")
+	header.Add("Baggage", "tenant=a")
+
+	// injecting twice keeps one member per key
+	tracing.InjectBaggage(ctx, header)
+	tracing.InjectBaggage(ctx, header)
+	if b := header.Values("Baggage"); len(b) != 1 || b[0] != "user_id=200,region=eu,tenant=b" {
+		t.Errorf("unexpected baggage %q", b)
+	}
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TruncatedMarker is appended to field values truncated to MaxFieldLength.
+var TruncatedMarker = "...(truncated)"
+
+// filterCore drops the fields of the entries which are not included or are
+// excluded, and truncates string values, before they are encoded.
+type filterCore struct {
+	zapcore.Core
+	include map[string]bool
+	exclude map[string]bool
+	maxLen  int
+}
+
+// newFilterCore wraps core with the field filters of the sink, core is
+// returned if the sink has none.
+func newFilterCore(core zapcore.Core, include []string, exclude []string, maxLen int) zapcore.Core {
+	if len(include) == 0 && len(exclude) == 0 && maxLen <= 0 {
+		return core
+	}
+	c := &filterCore{Core: core, maxLen: maxLen}
+	if len(include) > 0 {
+		c.include = make(map[string]bool, len(include))
+		for _, k := range include {
+			c.include[k] = true
+		}
+	}
+	if len(exclude) > 0 {
+		c.exclude = make(map[string]bool, len(exclude))
+		for _, k := range exclude {
+			c.exclude[k] = true
+		}
+	}
+	return c
+}
+
+func (c *filterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &filterCore{
+		Core:    c.Core.With(c.filter(fields)),
+		include: c.include,
+		exclude: c.exclude,
+		maxLen:  c.maxLen,
+	}
+}
+
+func (c *filterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *filterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.filter(fields))
+}
+
+func (c *filterCore) filter(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		// fields which add no key, e.g. the span carrier, are kept
+		if f.Type != zapcore.SkipType && f.Type != zapcore.NamespaceType {
+			if c.include != nil && !c.include[f.Key] {
+				continue
+			}
+			if c.exclude[f.Key] {
+				continue
+			}
+		}
+		if c.maxLen > 0 {
+			f = truncateField(f, c.maxLen)
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// truncateField truncates the string, byte string and error values longer
+// than maxLen bytes and appends TruncatedMarker.
+func truncateField(f zapcore.Field, maxLen int) zapcore.Field {
+	var s string
+	switch f.Type {
+	case zapcore.StringType:
+		s = f.String
+	case zapcore.ByteStringType:
+		s = string(f.Interface.([]byte))
+	case zapcore.ErrorType:
+		err, ok := f.Interface.(error)
+		if !ok || err == nil {
+			return f
+		}
+		s = err.Error()
+	default:
+		return f
+	}
+	if len(s) <= maxLen {
+		return f
+	}
+	return zap.String(f.Key, truncateString(s, maxLen)+TruncatedMarker)
+}
+
+// truncateString cuts s to at most n bytes without splitting a character.
+func truncateString(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"sort"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const gelfVersion = "1.1"
+
+// gelfEncoder encodes entries as Graylog GELF 1.1 messages, see
+// https://go2docs.graylog.org/current/getting_in_log_data/gelf.html
+//
+// The host is LHOST or LIP, or the hostname without them. The other fields are mapped as
+// in the ECS encoding and sent as additional fields, e.g. LAPP as
+// _service_name and TRACE_ID as _trace_id.
+type gelfEncoder struct {
+	mapEncoder
+	hostname string
+}
+
+func newGELFEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &gelfEncoder{newMapEncoder(cfg), Hostname()}
+}
+
+func (e *gelfEncoder) Clone() zapcore.Encoder {
+	return &gelfEncoder{e.clone(), e.hostname}
+}
+
+func (e *gelfEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	doc := ecsFields(e.mapEncoder, ent, fields)
+
+	host := e.hostname
+	for _, key := range []string{"host.ip", "host.hostname"} {
+		if v, ok := doc[key].(string); ok && v != "" {
+			host = v
+			delete(doc, key)
+		}
+	}
+	fullMessage, _ := doc["error.stack_trace"].(string)
+	delete(doc, "error.stack_trace")
+
+	buf := _bufferPool.Get()
+	buf.AppendString(`{"version":`)
+	appendJSON(buf, gelfVersion)
+	buf.AppendString(`,"host":`)
+	appendJSON(buf, host)
+	buf.AppendString(`,"short_message":`)
+	appendJSON(buf, ent.Message)
+	if fullMessage != "" {
+		buf.AppendString(`,"full_message":`)
+		appendJSON(buf, ent.Message+"\n"+fullMessage)
+	}
+	buf.AppendString(`,"timestamp":`)
+	appendJSON(buf, float64(ent.Time.UnixMilli())/1000)
+	buf.AppendString(`,"level":`)
+	buf.AppendInt(int64(gelfLevel(ent.Level)))
+
+	extra := map[string]interface{}{}
+	for k, v := range doc {
+		flatten(k, v, ".", func(key string, v interface{}) {
+			extra[gelfFieldName(key)] = gelfValue(v)
+		})
+	}
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf.AppendByte(',')
+		appendJSON(buf, k)
+		buf.AppendByte(':')
+		appendJSON(buf, extra[k])
+	}
+	buf.AppendByte('}')
+	buf.AppendString(e.lineEnding())
+	return buf, nil
+}
+
+// gelfLevel returns the syslog severity of the level.
+func gelfLevel(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// gelfFieldName returns the additional field name of key, which is prefixed
+// by '_'. Characters other than word characters and '-' are replaced by '_'.
+func gelfFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, key)
+	if name == "id" {
+		// _id is reserved
+		name = "id_"
+	}
+	return "_" + name
+}
+
+// gelfValue returns v as a number or a string, the only types allowed.
+func gelfValue(v interface{}) interface{} {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, string:
+		return v
+	}
+	return textValue(v)
+}
@@ -0,0 +1,33 @@
+package tracing
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// The placeholders used when Config.Golden is set.
+var (
+	GoldenTime = time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+)
+
+const (
+	GoldenPID    = 1
+	GoldenIP     = "192.0.2.1"
+	GoldenCaller = "<caller>"
+)
+
+// goldenClock always returns GoldenTime.
+type goldenClock struct{}
+
+func (goldenClock) Now() time.Time {
+	return GoldenTime
+}
+
+func (goldenClock) NewTicker(d time.Duration) *time.Ticker {
+	return time.NewTicker(d)
+}
+
+func goldenCallerEncoder(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(GoldenCaller)
+}
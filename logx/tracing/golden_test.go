@@ -0,0 +1,80 @@
+package tracing_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+	"go.uber.org/zap"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// logGolden writes a fixed set of entries with every encoding feature we rely
+// on and returns the file content.
+func logGolden(t *testing.T, encoding string) []byte {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "golden.log")
+	config := NewTestConfig(filename)
+	config.FileEncodeing = encoding
+	logger := tracing.NewLogger(config, tracing.WithGolden())
+
+	ctx := tracing.NewTraceCtx("golden-trace")
+	logger.Debug(ctx, "debug message")
+	logger.Infof(ctx, "user %d logged in", 100)
+	logger.With("order_id", "o-1").Warn(ctx, "order is slow")
+	logger.WithField(zap.Int("count", 3), zap.Bool("ok", false)).Error(ctx, "batch failed")
+	logger.Err(ctx, fmt.Errorf("query: %w", errors.New("timeout")), "load failed")
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s, run go test -update if the change is intended\ngot:\n%s\nwant:\n%s", golden, got, want)
+	}
+}
+
+func TestGoldenJSON(t *testing.T) {
+	assertGolden(t, "json.golden", logGolden(t, "json"))
+}
+
+func TestGoldenConsole(t *testing.T) {
+	assertGolden(t, "console.golden", logGolden(t, "console"))
+}
+
+func TestGoldenPretty(t *testing.T) {
+	assertGolden(t, "pretty.golden", logGolden(t, "pretty"))
+}
+
+func TestGoldenLogfmt(t *testing.T) {
+	assertGolden(t, "logfmt.golden", logGolden(t, "logfmt"))
+}
+
+func TestGoldenECS(t *testing.T) {
+	assertGolden(t, "ecs.golden", logGolden(t, "ecs"))
+}
+
+func TestGoldenGELF(t *testing.T) {
+	assertGolden(t, "gelf.golden", logGolden(t, "gelf"))
+}
@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultHookQueueSize = 1024
+
+// Hook is called with every entry and its fields, including the fields
+// added by With.
+type Hook func(zapcore.Entry, []zapcore.Field) error
+
+// hookEntry is an entry queued for the hooks.
+type hookEntry struct {
+	entry  zapcore.Entry
+	fields []zapcore.Field
+}
+
+// runHooks calls the hooks with every entry of a batch, it is run by a
+// single goroutine so hooks are called in the order the entries were
+// written.
+func runHooks(hooks []Hook) func([]interface{}) {
+	return func(batch []interface{}) {
+		for _, item := range batch {
+			e := item.(hookEntry)
+			for _, hook := range hooks {
+				if err := hook(e.entry, e.fields); err != nil {
+					fmt.Fprintf(os.Stderr, "%v hook error: %v\n", time.Now(), err)
+				}
+			}
+		}
+	}
+}
+
+type hookCore struct {
+	zapcore.LevelEnabler
+	runner *asyncRunner
+	fields []zapcore.Field
+}
+
+func newHookCore(config Config) *hookCore {
+	size := config.HookQueueSize
+	if size <= 0 {
+		size = defaultHookQueueSize
+	}
+	return &hookCore{
+		LevelEnabler: parseLevel(config.HookLevel),
+		runner:       newAsyncRunner("hooks "+config.AppName, size, 1, 0, runHooks(config.Hooks)),
+	}
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	return &hookCore{
+		LevelEnabler: c.LevelEnabler,
+		runner:       c.runner,
+		fields:       append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	c.runner.push(hookEntry{entry: ent, fields: all})
+	return nil
+}
+
+func (c *hookCore) Sync() error {
+	c.runner.flush()
+	return nil
+}
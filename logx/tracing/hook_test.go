@@ -0,0 +1,142 @@
+package tracing_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWithHooks(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		entries []zapcore.Entry
+		traceID string
+		app     string
+	)
+	hook := func(ent zapcore.Entry, fields []zapcore.Field) error {
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, ent)
+		for _, f := range fields {
+			switch f.Key {
+			case "TRACE_ID":
+				traceID = f.String
+			case "LAPP":
+				app = f.String
+			}
+		}
+		return nil
+	}
+
+	config := tracing.NewStdConfig()
+	config.EnableConsole = false
+	config.AppName = "hook_test"
+	logger := tracing.NewLogger(config, tracing.WithHooks(hook), tracing.WithHookLevel("warn"))
+
+	ctx := tracing.NewTraceCtx("hook-trace")
+	logger.Info(ctx, "info is below hook level")
+	logger.Warn(ctx, "warn")
+	logger.Error(ctx, "error")
+	logger.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(entries) != 2 {
+		t.Fatalf("hook got %d entries, want 2", len(entries))
+	}
+	if entries[0].Message != "warn" || entries[1].Message != "error" {
+		t.Errorf("hook got messages %q, %q", entries[0].Message, entries[1].Message)
+	}
+	if traceID != "hook-trace" {
+		t.Errorf("hook got TRACE_ID %q, want hook-trace", traceID)
+	}
+	if app != "hook_test" {
+		t.Errorf("hook got LAPP %q, want hook_test", app)
+	}
+}
+
+func TestHooksDoNotBlock(t *testing.T) {
+	block := make(chan struct{})
+	hook := func(zapcore.Entry, []zapcore.Field) error {
+		<-block
+		return nil
+	}
+
+	config := tracing.NewStdConfig()
+	config.EnableConsole = false
+	config.HookQueueSize = 1
+	config.AppName = "hook_drop_test"
+	logger := tracing.NewLogger(config, tracing.WithHooks(hook))
+
+	for i := 0; i < 10; i++ {
+		logger.Info(context.Background(), "must not block")
+	}
+	if s := queueStatus("hooks hook_drop_test"); s == nil || s.Dropped < 8 || s.Capacity != 1 {
+		t.Errorf("unexpected queue status %+v", s)
+	}
+	close(block)
+
+	// Close stops the runner, and the later entries are dropped
+	logger.Close()
+	logger.Info(context.Background(), "after close")
+	if s := queueStatus("hooks hook_drop_test"); s != nil {
+		t.Errorf("queue status %+v after Close", s)
+	}
+}
+
+func queueStatus(name string) *tracing.QueueStatus {
+	for _, s := range tracing.QueueStatuses() {
+		if s.Name == name {
+			return &s
+		}
+	}
+	return nil
+}
+
+func TestReplaceGlobalKeepsHooks(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		messages []string
+	)
+	hook := func(ent zapcore.Entry, _ []zapcore.Field) error {
+		mu.Lock()
+		defer mu.Unlock()
+		messages = append(messages, ent.Message)
+		return nil
+	}
+
+	config := tracing.NewStdConfig()
+	config.EnableConsole = false
+	config.AppName = "hook_global_test"
+	logger := tracing.NewLogger(config, tracing.WithHooks(hook))
+	defer logger.Close()
+
+	// the given logger is installed, without a second runner
+	restore := tracing.ReplaceLogger(logger)
+	tracing.Info(context.Background(), "global")
+	held := tracing.GetLogger()
+	restore()
+
+	n := 0
+	for _, s := range tracing.QueueStatuses() {
+		if s.Name == "hooks hook_global_test" {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("%d hook queues, want 1", n)
+	}
+
+	// the replaced logger is not closed and keeps its hooks
+	held.Info(context.Background(), "held")
+	logger.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(messages) != 2 || messages[0] != "global" || messages[1] != "held" {
+		t.Errorf("hook got %q", messages)
+	}
+}
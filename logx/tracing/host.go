@@ -0,0 +1,221 @@
+package tracing
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// EnvPodIP is the environment variable which overrides the source IP,
+	// e.g. set from status.podIP by the Kubernetes downward API.
+	EnvPodIP = "POD_IP"
+
+	// EnvPodName, EnvPodNamespace and EnvNodeName are the environment
+	// variables of the Kubernetes metadata fields.
+	EnvPodName      = "POD_NAME"
+	EnvPodNamespace = "POD_NAMESPACE"
+	EnvNodeName     = "NODE_NAME"
+)
+
+// The placeholders used when Config.Golden is set.
+const (
+	GoldenHostname    = "golden-host"
+	GoldenContainerID = "<container>"
+)
+
+var (
+	_ipCache sync.Map
+
+	_hostnameOnce sync.Once
+	_hostname     string
+
+	_containerIDOnce sync.Once
+	_containerID     string
+
+	containerIDRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+)
+
+// GetIP returns the first IPv4 address of the network interface eth, "" is
+// returned if it has none or can not be read. Unlike LookupIP, there is no
+// fallback to another interface.
+func GetIP(eth string) string {
+	ifi, err := net.InterfaceByName(eth)
+	if err != nil {
+		return ""
+	}
+
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return ""
+	}
+
+	for _, address := range addrs {
+		if ipnet, ok := address.(*net.IPNet); ok {
+			if ipnet.IP.To4() != nil {
+				return ipnet.IP.String()
+			}
+		}
+	}
+
+	return ""
+}
+
+// LookupIP returns the IPv4 or IPv6 address of the network interface eth.
+// The POD_IP environment variable overrides it, and the first up
+// non-loopback interface is used if eth is empty or has no address. A found
+// address is cached, "" is returned if none is found, and the interfaces
+// are looked up again by the next call.
+func LookupIP(eth string, ipv6 bool) string {
+	if ip := net.ParseIP(os.Getenv(EnvPodIP)); ip != nil && (ip.To4() == nil) == ipv6 {
+		return ip.String()
+	}
+
+	key := eth + "/" + strconv.FormatBool(ipv6)
+	if ip, ok := _ipCache.Load(key); ok {
+		return ip.(string)
+	}
+	ip := lookupInterfaceIP(eth, ipv6)
+	if ip != "" {
+		_ipCache.Store(key, ip)
+	}
+	return ip
+}
+
+func lookupInterfaceIP(eth string, ipv6 bool) string {
+	if eth != "" {
+		if ifi, err := net.InterfaceByName(eth); err == nil {
+			if ip := interfaceIP(ifi, ipv6); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	ifis, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	for i := range ifis {
+		ifi := &ifis[i]
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if ip := interfaceIP(ifi, ipv6); ip != "" {
+			return ip
+		}
+	}
+	return ""
+}
+
+// interfaceIP returns the first global unicast address of the interface.
+func interfaceIP(ifi *net.Interface, ipv6 bool) string {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return ""
+	}
+	for _, address := range addrs {
+		ipnet, ok := address.(*net.IPNet)
+		if !ok || !ipnet.IP.IsGlobalUnicast() || (ipnet.IP.To4() == nil) != ipv6 {
+			continue
+		}
+		return ipnet.IP.String()
+	}
+	return ""
+}
+
+// Hostname returns the host name reported by the kernel, it is cached.
+func Hostname() string {
+	_hostnameOnce.Do(func() {
+		_hostname, _ = os.Hostname()
+	})
+	return _hostname
+}
+
+// ContainerID returns the ID of the container the process runs in, found
+// in /proc/self/cgroup or /proc/self/mountinfo, it is cached. "" is returned
+// outside a container.
+func ContainerID() string {
+	_containerIDOnce.Do(func() {
+		_containerID = findContainerID("/proc/self/cgroup")
+		if _containerID == "" {
+			// with cgroup v2 the cgroup is "/", the ID is in the mounts
+			// of the container's files, e.g. /etc/hostname
+			_containerID = findContainerID("/proc/self/mountinfo")
+		}
+	})
+	return _containerID
+}
+
+func findContainerID(filename string) string {
+	f, err := os.Open(filename)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id := containerIDRegexp.FindString(scanner.Text()); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// hostFields returns the fields of the application and the host added to
+// every entry.
+func hostFields(config Config) []zap.Field {
+	fields := []zap.Field{zap.String("LAPP", config.AppName)}
+
+	if config.EnablePID {
+		pid := os.Getpid()
+		if config.Golden {
+			pid = GoldenPID
+		}
+		fields = append(fields, zap.Int("LPID", pid))
+	}
+
+	if config.EnableSourceIP {
+		ip := GoldenIP
+		if !config.Golden {
+			ip = LookupIP(config.SourceEth, config.SourceIPv6)
+		}
+		fields = append(fields, zap.String("LIP", ip))
+	}
+
+	if config.EnableHostname {
+		hostname := GoldenHostname
+		if !config.Golden {
+			hostname = Hostname()
+		}
+		fields = append(fields, zap.String("LHOST", hostname))
+	}
+
+	if config.EnableContainerID {
+		id := GoldenContainerID
+		if !config.Golden {
+			id = ContainerID()
+		}
+		if id != "" {
+			fields = append(fields, zap.String("LCONTAINER", id))
+		}
+	}
+
+	if config.EnableK8sMeta {
+		for _, kv := range [][2]string{
+			{"LPOD", EnvPodName},
+			{"LNAMESPACE", EnvPodNamespace},
+			{"LNODE", EnvNodeName},
+		} {
+			if v := os.Getenv(kv[1]); v != "" {
+				fields = append(fields, zap.String(kv[0], v))
+			}
+		}
+	}
+	return fields
+}
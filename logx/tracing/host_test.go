@@ -0,0 +1,72 @@
+package tracing_test
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+func TestLookupIP(t *testing.T) {
+	// GetIP has no fallback to another interface, and prints nothing
+	stdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	ip := tracing.GetIP("no-such-eth")
+	os.Stdout = stdout
+	w.Close()
+	printed, _ := io.ReadAll(r)
+	if ip != "" || len(printed) != 0 {
+		t.Errorf("GetIP returns %q and prints %q, want empty", ip, printed)
+	}
+	if ip := tracing.LookupIP("no-such-eth", false); ip != "" && net.ParseIP(ip).To4() == nil {
+		t.Errorf("LookupIP returns %q, want an IPv4 address or empty", ip)
+	}
+	if ip := tracing.LookupIP("", true); ip != "" && net.ParseIP(ip).To4() != nil {
+		t.Errorf("LookupIP returns %q, want an IPv6 address or empty", ip)
+	}
+
+	t.Setenv(tracing.EnvPodIP, "10.1.2.3")
+	if ip := tracing.LookupIP("no-such-eth", false); ip != "10.1.2.3" {
+		t.Errorf("POD_IP is not used, got %q", ip)
+	}
+	if ip := tracing.GetIP("no-such-eth"); ip != "" {
+		t.Errorf("GetIP uses POD_IP %q", ip)
+	}
+	if ip := tracing.LookupIP("", true); ip == "10.1.2.3" {
+		t.Errorf("IPv4 POD_IP is used for IPv6")
+	}
+}
+
+func TestHostFields(t *testing.T) {
+	t.Setenv(tracing.EnvPodName, "api-7d9f")
+	t.Setenv(tracing.EnvPodNamespace, "prod")
+	t.Setenv(tracing.EnvPodIP, "10.1.2.3")
+
+	filename := filepath.Join(t.TempDir(), "host.log")
+	config := NewTestConfig(filename)
+	config.SourceEth = ""
+	config.EnableHostname = true
+	config.EnableK8sMeta = true
+	logger := tracing.NewLogger(config)
+	logger.Info(tracing.NewTraceCtx("t1"), "hello")
+
+	hostname, _ := os.Hostname()
+	e := readEntries(t, filename)[0]
+	for key, want := range map[string]interface{}{
+		"LIP":        "10.1.2.3",
+		"LHOST":      hostname,
+		"LPOD":       "api-7d9f",
+		"LNAMESPACE": "prod",
+	} {
+		if e[key] != want {
+			t.Errorf("%s = %v, want %v", key, e[key], want)
+		}
+	}
+	if _, ok := e["LNODE"]; ok {
+		t.Errorf("LNODE is logged without NODE_NAME")
+	}
+}
@@ -1,11 +1,9 @@
 package tracing
 
 import (
-	"bytes"
 	"context"
 	"log"
 	"os"
-	"strings"
 	"sync"
 
 	"go.uber.org/zap"
@@ -25,20 +23,30 @@ func Init(filename string) {
 	ReplaceStdLog()
 }
 
+// SetConfig replaces the global logger with a logger of the config. The
+// returned function restores the previous logger.
 func SetConfig(config Config) func() {
-	_globalMu.Lock()
-	prev := _logger
-	_logger = NewLogger(config, WithGlobalCallerSkip(1))
-	_globalMu.Unlock()
-	return func() { ReplaceLogger(prev) }
+	return replaceGlobal(NewLogger(config, WithGlobalCallerSkip(1)))
 }
 
+// ReplaceLogger replaces the global logger with logger, which is shared with
+// it, e.g. its files and hooks. The returned function restores the previous
+// logger.
 func ReplaceLogger(logger *VLogger) func() {
+	config := logger.config
+	config.GlobalCallerSkip++
+	return replaceGlobal(&VLogger{logger.log.WithOptions(zap.AddCallerSkip(1)), config, logger.closers})
+}
+
+// replaceGlobal replaces the global logger. The previous logger is not
+// closed, as the loggers derived from it may still be in use, the caller
+// closes it when they are done.
+func replaceGlobal(logger *VLogger) func() {
 	_globalMu.Lock()
 	prev := _logger
-	_logger = NewLogger(logger.config, WithGlobalCallerSkip(1))
+	_logger = logger
 	_globalMu.Unlock()
-	return func() { ReplaceLogger(prev) }
+	return func() { replaceGlobal(prev) }
 }
 
 func GetLogger() *VLogger {
@@ -133,6 +141,38 @@ func Panicf(ctx context.Context, format string, args ...interface{}) {
 	_logger.Panicf(ctx, format, args...)
 }
 
+// Err logs a message at level Error on the VLogger, with the error's type,
+// message, wrapped causes and stack trace.
+func Err(ctx context.Context, err error, msg string, fields ...zap.Field) {
+	_logger.Err(ctx, err, msg, fields...)
+}
+
+// ErrAt logs a message at the specified level on the VLogger, with the error's
+// type, message, wrapped causes and stack trace.
+func ErrAt(ctx context.Context, level string, err error, msg string, fields ...zap.Field) {
+	_logger.ErrAt(ctx, level, err, msg, fields...)
+}
+
+// Audit appends an entry to the audit file of the VLogger, see
+// VLogger.Audit.
+func Audit(ctx context.Context, actor, action, resource, outcome string, fields ...zap.Field) error {
+	return _logger.Audit(ctx, actor, action, resource, outcome, fields...)
+}
+
+// Close flushes the entries and closes the sinks of the VLogger, see
+// VLogger.Close.
+func Close() error {
+	return _logger.Close()
+}
+
+// HandlePanic recovers a panic of the goroutine, logs it and panics again,
+// see VLogger.HandlePanic.
+func HandlePanic(ctx context.Context) {
+	if v := recover(); v != nil {
+		_logger.Repanic(ctx, v)
+	}
+}
+
 // With return a logger with an extra field.
 func With(key string, value interface{}) *VLogger {
 	return _logger.With(key, value)
@@ -154,9 +194,10 @@ func AddCallerSkip(skip int) *VLogger {
 }
 
 // RedirectStdLog redirects output from the standard library's package-global
-// logger to the supplied logger at InfoLevel. Since zap already handles caller
-// annotations, timestamps, etc., it automatically disables the standard
-// library's annotations and prefixing.
+// logger to the supplied logger at InfoLevel, unless a line has a level
+// prefix such as "[ERROR]", "WARN:" or "level=error". Since zap already
+// handles caller annotations, timestamps, etc., it automatically disables the
+// standard library's annotations and prefixing.
 //
 // It returns a function to restore the original prefix and flags and reset the
 // standard library's output to os.Stderr.
@@ -165,7 +206,8 @@ func RedirectStdLog(l *VLogger) (func(), error) {
 }
 
 // RedirectStdLogAt redirects output from the standard library's package-global
-// logger to the supplied logger at the specified level. Since zap already
+// logger to the supplied logger at the specified level, unless a line has a
+// level prefix such as "[ERROR]", "WARN:" or "level=error". Since zap already
 // handles caller annotations, timestamps, etc., it automatically disables the
 // standard library's annotations and prefixing.
 //
@@ -184,44 +226,10 @@ func redirectStdLogAt(l *VLogger, level string) (func(), error) {
 	prefix := log.Prefix()
 	log.SetFlags(0)
 	log.SetPrefix("")
-	logFunc, err := levelToFunc(l, level)
-	if err != nil {
-		return nil, err
-	}
-	log.SetOutput(&loggerWriter{NewTraceCtx("stdlog"), logFunc})
+	log.SetOutput(newLoggerWriter(l, level))
 	return func() {
 		log.SetFlags(flags)
 		log.SetPrefix(prefix)
 		log.SetOutput(os.Stderr)
 	}, nil
 }
-
-func levelToFunc(l *VLogger, lvl string) (func(context.Context, ...interface{}), error) {
-	switch strings.ToLower(lvl) {
-	case "debug":
-		return l.Debug, nil
-	case "info":
-		return l.Info, nil
-	case "warn":
-		return l.Warn, nil
-	case "error":
-		return l.Error, nil
-	case "panic":
-		return l.Panic, nil
-	case "fatal":
-		return l.Fatal, nil
-	default:
-		return l.Info, nil
-	}
-}
-
-type loggerWriter struct {
-	ctx     context.Context
-	logFunc func(context.Context, ...interface{})
-}
-
-func (l *loggerWriter) Write(p []byte) (int, error) {
-	p = bytes.TrimSpace(p)
-	l.logFunc(l.ctx, string(p))
-	return len(p), nil
-}
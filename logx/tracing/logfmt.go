@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// logfmtEncoder encodes entries as logfmt, one line of key=value pairs. The
+// time, level, caller and message use the keys of the encoder config, nested
+// objects are flattened with dotted keys.
+type logfmtEncoder struct {
+	mapEncoder
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{newMapEncoder(cfg)}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	return &logfmtEncoder{e.clone()}
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf := _bufferPool.Get()
+
+	if e.cfg.TimeKey != zapcore.OmitKey {
+		e.appendPair(buf, e.cfg.TimeKey, e.value(ent.Time))
+	}
+	if e.cfg.LevelKey != zapcore.OmitKey {
+		level := interface{}(ent.Level.String())
+		if e.cfg.EncodeLevel != nil {
+			var p primitiveValues
+			e.cfg.EncodeLevel(ent.Level, &p)
+			level = p.value()
+		}
+		e.appendPair(buf, e.cfg.LevelKey, level)
+	}
+	if ent.LoggerName != "" && e.cfg.NameKey != zapcore.OmitKey {
+		e.appendPair(buf, e.cfg.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined && e.cfg.CallerKey != zapcore.OmitKey {
+		e.appendPair(buf, e.cfg.CallerKey, e.caller(ent.Caller))
+		if ent.Caller.Function != "" && e.cfg.FunctionKey != zapcore.OmitKey {
+			e.appendPair(buf, e.cfg.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if e.cfg.MessageKey != zapcore.OmitKey {
+		e.appendPair(buf, e.cfg.MessageKey, ent.Message)
+	}
+
+	for _, f := range e.fields(fields) {
+		flatten(f.key, f.value, ".", func(key string, v interface{}) {
+			e.appendPair(buf, key, e.value(v))
+		})
+	}
+
+	if ent.Stack != "" && e.cfg.StacktraceKey != zapcore.OmitKey {
+		e.appendPair(buf, e.cfg.StacktraceKey, ent.Stack)
+	}
+
+	buf.AppendString(e.lineEnding())
+	return buf, nil
+}
+
+func (e *logfmtEncoder) appendPair(buf *buffer.Buffer, key string, value interface{}) {
+	if buf.Len() > 0 {
+		buf.AppendByte(' ')
+	}
+	buf.AppendString(quoteText(key))
+	buf.AppendByte('=')
+	buf.AppendString(quoteText(textValue(value)))
+}
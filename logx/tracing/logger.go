@@ -2,16 +2,15 @@ package tracing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"net"
 	"os"
 	"strings"
 
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
 var (
@@ -35,35 +34,18 @@ func GetTraceID(ctx context.Context) string {
 }
 
 type VLogger struct {
-	log    *zap.Logger
-	config Config
+	log     *zap.Logger
+	config  Config
+	closers closers
 }
 
-func newCore(level string, encoding string, w zapcore.WriteSyncer) (core zapcore.Core) {
-	encoderConfig := zap.NewProductionEncoderConfig()
-	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	encoderConfig.EncodeDuration = zapcore.NanosDurationEncoder
-	encoderConfig.TimeKey = "T"
-	encoderConfig.LevelKey = "L"
-	encoderConfig.MessageKey = "M"
-	encoderConfig.CallerKey = "LFILE"
-
-	var l zapcore.Level
-	switch strings.ToLower(level) {
-	case "debug":
-		l = zap.DebugLevel
-	case "info":
-		l = zap.InfoLevel
-	case "warn":
-		l = zap.WarnLevel
-	case "error":
-		l = zap.ErrorLevel
-	case "fatal":
-		l = zap.FatalLevel
-	case "panic":
-		l = zap.PanicLevel
-	default:
-		l = zap.InfoLevel
+// newCore returns a core writing to w, color is only used by the console
+// encodings when w is a terminal. The entries end with lineEnding, or a
+// newline if it is empty.
+func newCore(config Config, enab zapcore.LevelEnabler, encoding string, w zapcore.WriteSyncer, color bool, lineEnding string) (core zapcore.Core) {
+	encoderConfig := newEncoderConfig(config)
+	if lineEnding != "" {
+		encoderConfig.LineEnding = lineEnding
 	}
 
 	var e zapcore.Encoder
@@ -71,108 +53,126 @@ func newCore(level string, encoding string, w zapcore.WriteSyncer) (core zapcore
 	case "json":
 		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
 		e = zapcore.NewJSONEncoder(encoderConfig)
-	case "console":
-		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		e = zapcore.NewConsoleEncoder(encoderConfig)
+	case "pretty":
+		if config.TimeFormat == "" {
+			encoderConfig.EncodeTime = newTimeEncoder(prettyTimeFormat, config.TimeZone)
+		}
+		e = newPrettyEncoder(encoderConfig, color)
+	case "logfmt":
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		e = newLogfmtEncoder(encoderConfig)
+	case "ecs":
+		e = newECSEncoder(encoderConfig)
+	case "gelf":
+		e = newGELFEncoder(encoderConfig)
 	default:
-		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		if color {
+			encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
 		e = zapcore.NewConsoleEncoder(encoderConfig)
 	}
 
-	core = zapcore.NewCore(e, w, l)
+	core = zapcore.NewCore(e, w, enab)
 	return
 }
 
+// parseLevel converts a level name to a zapcore.Level, the default is info.
+func parseLevel(level string) zapcore.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return zap.DebugLevel
+	case "info":
+		return zap.InfoLevel
+	case "warn":
+		return zap.WarnLevel
+	case "error":
+		return zap.ErrorLevel
+	case "fatal":
+		return zap.FatalLevel
+	case "panic":
+		return zap.PanicLevel
+	default:
+		return zap.InfoLevel
+	}
+}
+
 func NewLogger(config Config, opts ...Option) *VLogger {
-	coreFlag := false
 	var core zapcore.Core
 
 	for _, opt := range opts {
 		opt(&config)
 	}
 
-	if config.EnableFile {
-		hook := lumberjack.Logger{
-			Filename:   config.Filename,
-			MaxSize:    config.MaxSize,
-			MaxBackups: config.MaxBackups,
-			MaxAge:     config.MaxAge,
-			LocalTime:  config.LocalTime,
-			Compress:   config.Compress,
+	var cores []zapcore.Core
+	var closers closers
+	for _, sink := range config.sinks() {
+		c, closer, err := newSinkCore(config, sink)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logx: %v\n", err)
+			continue
 		}
-		w := zapcore.AddSync(&hook)
-		filecore := newCore(config.FileLevel, config.FileEncodeing, w)
-
-		if coreFlag {
-			core = zapcore.NewTee(core, filecore)
-		} else {
-			core = filecore
-			coreFlag = true
+		cores = append(cores, c)
+		if closer != nil {
+			closers = append(closers, closer)
 		}
 	}
 
-	if config.EnableConsole {
-		w := zapcore.Lock(os.Stderr)
-		consolecore := newCore(config.ConsoleLevel, config.ConsoleEncodeing, w)
-
-		if coreFlag {
-			core = zapcore.NewTee(core, consolecore)
-		} else {
-			core = consolecore
-			coreFlag = true
-		}
-	}
-
-	if !config.EnableFile && !config.EnableConsole {
+	if len(cores) > 0 {
+		core = zapcore.NewTee(cores...)
+	} else {
 		core = zapcore.NewCore(
 			zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
 			zapcore.AddSync(ioutil.Discard),
 			zap.PanicLevel)
 	}
 
-	fields := []zap.Field{zap.String("LAPP", config.AppName)}
-	if config.EnablePID {
-		fields = append(fields, zap.Int("LPID", os.Getpid()))
+	if config.EnableSpanEvents {
+		core = zapcore.NewTee(core, newSpanEventCore(config))
 	}
 
-	if config.EnableSourceIP {
-		fields = append(fields, zap.String("LIP", GetIP(config.SourceEth)))
+	if config.EnableOTLP {
+		otlp := newOTLPCore(config)
+		core = zapcore.NewTee(core, otlp)
+		closers = append(closers, otlp.exporter)
 	}
 
-	core = core.With(fields)
+	if len(config.Hooks) > 0 {
+		hooks := newHookCore(config)
+		core = zapcore.NewTee(core, hooks)
+		closers = append(closers, hooks.runner)
+	}
 
-	zapOption := []zap.Option{}
-	if config.EnableCaller {
-		zapOption = append(zapOption, zap.AddCaller(), zap.AddCallerSkip(config.GlobalCallerSkip+1))
+	if config.EnableAudit {
+		closers = append(closers, auditCloser{config})
 	}
 
-	l := zap.New(core, zapOption...)
+	if config.WrapCore != nil {
+		core = config.WrapCore(core)
+	}
 
-	return &VLogger{l, config}
-}
+	core = core.With(hostFields(config))
 
-func GetIP(eth string) string {
-	ifi, err := net.InterfaceByName(eth)
-	if err != nil {
-		fmt.Println(err)
-		return ""
+	// Fatal and Panic sync all the cores before the process exits or panics
+	zapOption := []zap.Option{
+		zap.WithFatalHook(crashHook{core: core, config: config, then: zapcore.WriteThenFatal}),
+		zap.WithPanicHook(crashHook{core: core, config: config, then: zapcore.WriteThenPanic}),
+	}
+	if config.EnableCaller {
+		zapOption = append(zapOption, zap.AddCaller(), zap.AddCallerSkip(config.GlobalCallerSkip+1))
 	}
 
-	addrs, err := ifi.Addrs()
-	if err != nil {
-		fmt.Println(err)
-		return ""
+	if config.StacktraceLevel != "" {
+		zapOption = append(zapOption, zap.AddStacktrace(parseLevel(config.StacktraceLevel)))
 	}
 
-	for _, address := range addrs {
-		if ipnet, ok := address.(*net.IPNet); ok {
-			if ipnet.IP.To4() != nil {
-				return ipnet.IP.String()
-			}
-		}
+	if config.Golden {
+		zapOption = append(zapOption, zap.WithClock(goldenClock{}))
 	}
 
-	return ""
+	l := zap.New(core, zapOption...)
+
+	return &VLogger{l, config, closers}
 }
 
 func (l *VLogger) getFields(ctx context.Context) (fields []zap.Field) {
@@ -181,10 +181,23 @@ func (l *VLogger) getFields(ctx context.Context) (fields []zap.Field) {
 		if span.HasTraceID() {
 			fields = append(fields, zap.String("TRACE_ID", span.TraceID().String()))
 			fields = append(fields, zap.String("SPAN_ID", span.SpanID().String()))
+			if s, ok := trace.SpanFromContext(ctx).(*Span); ok && s.parent.HasSpanID() {
+				fields = append(fields, zap.String("PARENT_SPAN_ID", s.parent.SpanID().String()))
+			}
+			// the X-Request-ID which is not the trace ID of the span
+			if id := GetTraceID(ctx); id != "" && id != span.TraceID().String() {
+				fields = append(fields, zap.String("REQUEST_ID", id))
+			}
 		} else {
 			fields = append(fields, zap.String("TRACE_ID", GetTraceID(ctx)))
 		}
 	}
+	fields = append(fields, ContextFields(ctx)...)
+	if l.config.EnableSpanEvents {
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			fields = append(fields, spanField(span))
+		}
+	}
 	return
 }
 
@@ -262,6 +275,20 @@ func (l VLogger) Panicf(ctx context.Context, format string, args ...interface{})
 	l.log.Panic(fmt.Sprintf(format, args...), l.getFields(ctx)...)
 }
 
+// Err logs a message at level Error on the VLogger, with the error's type,
+// message, wrapped causes and stack trace.
+func (l VLogger) Err(ctx context.Context, err error, msg string, fields ...zap.Field) {
+	l.log.Error(msg, append(append(l.getFields(ctx), fields...), ErrorField(err))...)
+}
+
+// ErrAt logs a message at the specified level on the VLogger, with the error's
+// type, message, wrapped causes and stack trace.
+func (l VLogger) ErrAt(ctx context.Context, level string, err error, msg string, fields ...zap.Field) {
+	if ce := l.log.Check(parseLevel(level), msg); ce != nil {
+		ce.Write(append(append(l.getFields(ctx), fields...), ErrorField(err))...)
+	}
+}
+
 func (l *VLogger) zapFields(fields map[string]interface{}) []zap.Field {
 	zapFields := make([]zap.Field, len(fields))
 	for k, v := range fields {
@@ -282,10 +309,26 @@ func (l *VLogger) Withs(fields map[string]interface{}) *VLogger {
 
 // WithField return a logger with extra zap fields.
 func (l *VLogger) WithField(fields ...zap.Field) *VLogger {
-	return &VLogger{l.log.With(fields...), l.config}
+	return &VLogger{l.log.With(fields...), l.config, l.closers}
 }
 
 // AddCallerSkip return a logger with new caller skip.
 func (l *VLogger) AddCallerSkip(skip int) *VLogger {
-	return &VLogger{l.log.WithOptions(zap.AddCallerSkip(skip)), l.config}
+	return &VLogger{l.log.WithOptions(zap.AddCallerSkip(skip)), l.config, l.closers}
+}
+
+// Named return a logger with name appended to the logger name.
+func (l *VLogger) Named(name string) *VLogger {
+	return &VLogger{l.log.Named(name), l.config, l.closers}
+}
+
+// Enabled returns true if the logger logs messages at level.
+func (l *VLogger) Enabled(level string) bool {
+	return l.log.Core().Enabled(parseLevel(level))
+}
+
+// Sync flushes any buffered log entries, including the entries queued for
+// hooks and the audit file.
+func (l *VLogger) Sync() error {
+	return errors.Join(l.log.Sync(), syncAudit(l.config))
 }
@@ -0,0 +1,364 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultOTLPEndpoint      = "http://localhost:4318/v1/logs"
+	defaultOTLPBatchSize     = 512
+	defaultOTLPQueueSize     = 2048
+	defaultOTLPFlushInterval = 1000
+
+	otlpScopeName = "github.com/kakabei/kfgolib/logx"
+)
+
+// The OTLP/HTTP JSON encoding of the logs service request, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string        `json:"stringValue,omitempty"`
+	BoolValue   *bool          `json:"boolValue,omitempty"`
+	IntValue    *string        `json:"intValue,omitempty"`
+	DoubleValue *float64       `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArray     `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKeyValues `json:"kvlistValue,omitempty"`
+}
+
+type otlpArray struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+type otlpKeyValues struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+func otlpString(s string) otlpAnyValue {
+	return otlpAnyValue{StringValue: &s}
+}
+
+// otlpValue converts a value produced by zapcore.MapObjectEncoder.
+func otlpValue(v interface{}) otlpAnyValue {
+	switch v := v.(type) {
+	case string:
+		return otlpString(v)
+	case bool:
+		return otlpAnyValue{BoolValue: &v}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		s := fmt.Sprint(v)
+		return otlpAnyValue{IntValue: &s}
+	case float32:
+		f := float64(v)
+		return otlpAnyValue{DoubleValue: &f}
+	case float64:
+		return otlpAnyValue{DoubleValue: &v}
+	case []interface{}:
+		values := make([]otlpAnyValue, 0, len(v))
+		for _, e := range v {
+			values = append(values, otlpValue(e))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArray{values}}
+	case map[string]interface{}:
+		return otlpAnyValue{KvlistValue: &otlpKeyValues{otlpAttributes(v)}}
+	case time.Duration:
+		s := strconv.FormatInt(int64(v), 10)
+		return otlpAnyValue{IntValue: &s}
+	case time.Time:
+		return otlpString(v.Format(time.RFC3339Nano))
+	default:
+		return otlpString(fmt.Sprint(v))
+	}
+}
+
+// otlpAttributes converts a map to attributes sorted by key.
+func otlpAttributes(m map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, otlpKeyValue{Key: k, Value: otlpValue(m[k])})
+	}
+	return attrs
+}
+
+// otlpSeverity maps a level to the OpenTelemetry severity number.
+func otlpSeverity(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	case zapcore.DPanicLevel:
+		return 18
+	case zapcore.PanicLevel:
+		return 19
+	case zapcore.FatalLevel:
+		return 21
+	default:
+		return 0
+	}
+}
+
+func isHexID(s string, size int) bool {
+	if len(s) != size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && s != "00000000000000000000000000000000"[:size*2]
+}
+
+// otlpResourceKeys maps the fields of hostFields onto the OpenTelemetry
+// resource semantic conventions.
+var otlpResourceKeys = map[string]string{
+	"LAPP":       "service.name",
+	"LPID":       "process.pid",
+	"LIP":        "host.ip",
+	"LHOST":      "host.name",
+	"LCONTAINER": "container.id",
+	"LPOD":       "k8s.pod.name",
+	"LNAMESPACE": "k8s.namespace.name",
+	"LNODE":      "k8s.node.name",
+}
+
+// traceKeys are the fields of the trace context added by getFields and
+// Span.Logger.
+var traceKeys = []string{"TRACE_ID", "SPAN_ID", "PARENT_SPAN_ID"}
+
+// newOTLPRecord converts an entry and its fields to a log record. The fields
+// of hostFields are sent as resource attributes and are dropped, and
+// the TRACE_ID/SPAN_ID fields become the record's trace and span IDs.
+func newOTLPRecord(ent zapcore.Entry, fields []zapcore.Field) otlpLogRecord {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	attrs := enc.Fields
+
+	record := otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(ent.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       otlpSeverity(ent.Level),
+		SeverityText:         ent.Level.CapitalString(),
+		Body:                 otlpString(ent.Message),
+	}
+
+	if traceID, ok := attrs["TRACE_ID"].(string); ok && isHexID(traceID, 16) {
+		record.TraceID = traceID
+		delete(attrs, "TRACE_ID")
+	}
+	if spanID, ok := attrs["SPAN_ID"].(string); ok && isHexID(spanID, 8) {
+		record.SpanID = spanID
+		delete(attrs, "SPAN_ID")
+	}
+	for key := range otlpResourceKeys {
+		delete(attrs, key)
+	}
+
+	if ent.Caller.Defined {
+		attrs["code.filepath"] = ent.Caller.File
+		attrs["code.lineno"] = ent.Caller.Line
+		if ent.Caller.Function != "" {
+			attrs["code.function"] = ent.Caller.Function
+		}
+	}
+	if ent.LoggerName != "" {
+		attrs["logger"] = ent.LoggerName
+	}
+	if ent.Stack != "" {
+		attrs["stacktrace"] = ent.Stack
+	}
+
+	record.Attributes = otlpAttributes(attrs)
+	return record
+}
+
+// otlpExporter batches log records and posts them to the OTLP/HTTP endpoint
+// in a single goroutine.
+type otlpExporter struct {
+	endpoint string
+	headers  map[string]string
+	resource otlpResource
+	client   *http.Client
+	runner   *asyncRunner
+}
+
+func newOTLPExporter(config Config) *otlpExporter {
+	e := &otlpExporter{
+		endpoint: config.OTLPEndpoint,
+		headers:  config.OTLPHeaders,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	if e.endpoint == "" {
+		e.endpoint = defaultOTLPEndpoint
+	}
+	batchSize := config.OTLPBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultOTLPBatchSize
+	}
+	interval := time.Duration(config.OTLPFlushInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultOTLPFlushInterval * time.Millisecond
+	}
+	size := config.OTLPQueueSize
+	if size <= 0 {
+		size = defaultOTLPQueueSize
+	}
+
+	resource := map[string]interface{}{}
+	for _, f := range encodeFields(hostFields(config)) {
+		resource[otlpResourceKeys[f.key]] = f.value
+	}
+	e.resource = otlpResource{Attributes: otlpAttributes(resource)}
+
+	e.runner = newAsyncRunner("otlp "+e.endpoint, size, batchSize, interval, e.exportBatch)
+	return e
+}
+
+// exportBatch exports a batch of the runner.
+func (e *otlpExporter) exportBatch(batch []interface{}) {
+	records := make([]otlpLogRecord, len(batch))
+	for i, item := range batch {
+		records[i] = item.(otlpLogRecord)
+	}
+	if err := e.export(records); err != nil {
+		fmt.Fprintf(os.Stderr, "%v otlp export error: %v\n", time.Now(), err)
+	}
+}
+
+func (e *otlpExporter) export(records []otlpLogRecord) error {
+	body, err := json.Marshal(otlpLogsRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: e.resource,
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpScope{Name: otlpScopeName},
+				LogRecords: records,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %s", e.endpoint, res.Status)
+	}
+	return nil
+}
+
+// Close exports the queued records, stops the goroutine and closes the
+// idle connections.
+func (e *otlpExporter) Close() error {
+	e.runner.Close()
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+type otlpCore struct {
+	zapcore.LevelEnabler
+	exporter *otlpExporter
+	fields   []zapcore.Field
+}
+
+func newOTLPCore(config Config) *otlpCore {
+	return &otlpCore{
+		LevelEnabler: parseLevel(config.OTLPLevel),
+		exporter:     newOTLPExporter(config),
+	}
+}
+
+func (c *otlpCore) With(fields []zapcore.Field) zapcore.Core {
+	return &otlpCore{
+		LevelEnabler: c.LevelEnabler,
+		exporter:     c.exporter,
+		fields:       append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *otlpCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *otlpCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	c.exporter.runner.push(newOTLPRecord(ent, all))
+	return nil
+}
+
+func (c *otlpCore) Sync() error {
+	c.exporter.runner.flush()
+	return nil
+}
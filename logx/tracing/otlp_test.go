@@ -0,0 +1,131 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOTLPExport(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Authorization") != "token" {
+			t.Errorf("unexpected request %s %v", r.URL, r.Header)
+		}
+		req := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	config := tracing.NewStdConfig()
+	config.EnableConsole = false
+	config.AppName = "otlp_test"
+	config.EnableOTLP = true
+	config.OTLPEndpoint = server.URL + "/v1/logs"
+	config.OTLPHeaders = map[string]string{"Authorization": "token"}
+	logger := tracing.NewLogger(config)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x01, 0x02},
+		SpanID:  trace.SpanID{0x03},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+	logger.With("user_id", 100).Warn(ctx, "slow request")
+	logger.Debug(ctx, "debug is below the default level")
+	logger.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("got %d export requests, want 1", len(requests))
+	}
+
+	resourceLogs := requests[0]["resourceLogs"].([]interface{})[0].(map[string]interface{})
+	resource := attributes(resourceLogs["resource"].(map[string]interface{}))
+	if resource["service.name"] != "otlp_test" {
+		t.Errorf("unexpected resource %v", resource)
+	}
+
+	records := resourceLogs["scopeLogs"].([]interface{})[0].(map[string]interface{})["logRecords"].([]interface{})
+	if len(records) != 1 {
+		t.Fatalf("got %d log records, want 1", len(records))
+	}
+	record := records[0].(map[string]interface{})
+	if record["severityText"] != "WARN" || record["severityNumber"] != float64(13) {
+		t.Errorf("unexpected severity %v", record)
+	}
+	if record["body"].(map[string]interface{})["stringValue"] != "slow request" {
+		t.Errorf("unexpected body %v", record["body"])
+	}
+	if record["traceId"] != sc.TraceID().String() || record["spanId"] != sc.SpanID().String() {
+		t.Errorf("unexpected trace context %v %v", record["traceId"], record["spanId"])
+	}
+	attrs := attributes(record)
+	if attrs["user_id"] != "100" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+	if _, ok := attrs["LAPP"]; ok {
+		t.Errorf("LAPP must be a resource attribute, not a log attribute")
+	}
+}
+
+func TestOTLPClose(t *testing.T) {
+	var exported int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&exported, 1)
+	}))
+	defer server.Close()
+
+	config := tracing.NewStdConfig()
+	config.EnableConsole = false
+	config.EnableOTLP = true
+	config.OTLPEndpoint = server.URL + "/v1/logs"
+	config.OTLPFlushInterval = 60000
+	logger := tracing.NewLogger(config)
+
+	logger.Warn(context.Background(), "exported by close")
+	if s := queueStatus("otlp " + config.OTLPEndpoint); s == nil || s.Capacity != 2048 {
+		t.Errorf("unexpected queue status %+v", s)
+	}
+	logger.Close()
+	if atomic.LoadInt32(&exported) != 1 {
+		t.Errorf("got %d export requests, want 1", exported)
+	}
+	if s := queueStatus("otlp " + config.OTLPEndpoint); s != nil {
+		t.Errorf("queue status %+v after Close", s)
+	}
+
+	logger.Warn(context.Background(), "dropped after close")
+	logger.Sync()
+	if atomic.LoadInt32(&exported) != 1 {
+		t.Errorf("got %d export requests after Close, want 1", exported)
+	}
+}
+
+// attributes returns the first value of each attribute in obj.
+func attributes(obj map[string]interface{}) map[string]interface{} {
+	attrs := map[string]interface{}{}
+	list, _ := obj["attributes"].([]interface{})
+	for _, a := range list {
+		kv := a.(map[string]interface{})
+		for _, v := range kv["value"].(map[string]interface{}) {
+			attrs[kv["key"].(string)] = v
+		}
+	}
+	return attrs
+}
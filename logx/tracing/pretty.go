@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorDim     = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+
+	prettyTimeFormat  = "15:04:05.000"
+	prettyCallerWidth = 28
+	prettyTraceWidth  = 8
+	prettyIndent      = "    "
+)
+
+// prettyEncoder is a developer-focused console encoder. Each entry is one line
+// of time, level, caller, message, the shortened trace ID and key=value
+// fields, followed by errors and stacks on separate indented lines. The time
+// is encoded as Config.TimeFormat, or as prettyTimeFormat if it is not set.
+type prettyEncoder struct {
+	mapEncoder
+	color bool
+}
+
+func newPrettyEncoder(cfg zapcore.EncoderConfig, color bool) zapcore.Encoder {
+	return &prettyEncoder{newMapEncoder(cfg), color}
+}
+
+func (e *prettyEncoder) Clone() zapcore.Encoder {
+	return &prettyEncoder{e.clone(), e.color}
+}
+
+func (e *prettyEncoder) paint(buf *buffer.Buffer, color string, s string) {
+	if e.color && color != "" {
+		buf.AppendString(color)
+		buf.AppendString(s)
+		buf.AppendString(colorReset)
+		return
+	}
+	buf.AppendString(s)
+}
+
+// padRight pads s with spaces to width runes, fmt pads by bytes.
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+func levelColor(l zapcore.Level) string {
+	switch l {
+	case zapcore.DebugLevel:
+		return colorMagenta
+	case zapcore.InfoLevel:
+		return colorBlue
+	case zapcore.WarnLevel:
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
+
+func (e *prettyEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf := _bufferPool.Get()
+
+	e.paint(buf, colorDim, textValue(e.value(ent.Time)))
+	buf.AppendByte(' ')
+	e.paint(buf, levelColor(ent.Level), fmt.Sprintf("%-5s", ent.Level.CapitalString()))
+
+	if ent.Caller.Defined && e.cfg.CallerKey != zapcore.OmitKey {
+		caller := []rune(e.caller(ent.Caller))
+		if len(caller) > prettyCallerWidth {
+			caller = append([]rune("…"), caller[len(caller)-prettyCallerWidth+1:]...)
+		}
+		buf.AppendByte(' ')
+		e.paint(buf, colorDim, padRight(string(caller), prettyCallerWidth))
+	}
+
+	if ent.LoggerName != "" {
+		buf.AppendByte(' ')
+		e.paint(buf, colorCyan, ent.LoggerName+":")
+	}
+	buf.AppendByte(' ')
+	buf.AppendString(ent.Message)
+
+	all := e.fields(fields)
+
+	// the shortened trace ID directly follows the message.
+	for _, f := range all {
+		if traceID, ok := f.value.(string); ok && f.key == "TRACE_ID" && traceID != "" {
+			if len(traceID) > prettyTraceWidth {
+				traceID = traceID[:prettyTraceWidth]
+			}
+			buf.AppendByte(' ')
+			e.paint(buf, colorDim, "trace=")
+			e.paint(buf, colorCyan, traceID)
+		}
+	}
+
+	var extra []field
+	for _, f := range all {
+		switch f.key {
+		case "TRACE_ID":
+		case "error", "errorVerbose", "stacktrace", e.cfg.StacktraceKey:
+			extra = append(extra, f)
+		default:
+			buf.AppendByte(' ')
+			e.paint(buf, colorDim, f.key+"=")
+			buf.AppendString(quoteText(textValue(f.value)))
+		}
+	}
+
+	for _, f := range extra {
+		e.appendBlock(buf, f.key, f.value)
+	}
+	if ent.Stack != "" {
+		e.appendBlock(buf, "stacktrace", ent.Stack)
+	}
+
+	buf.AppendString(e.lineEnding())
+	return buf, nil
+}
+
+// appendBlock appends an error or a stack on separate indented lines.
+func (e *prettyEncoder) appendBlock(buf *buffer.Buffer, key string, value interface{}) {
+	color := colorDim
+	if key == "error" {
+		color = colorRed
+	}
+
+	if obj, ok := value.(map[string]interface{}); ok && key == "error" {
+		buf.AppendByte('\n')
+		buf.AppendString(prettyIndent)
+		e.paint(buf, color, fmt.Sprintf("error: %v: %v", obj["type"], obj["msg"]))
+		if causes, ok := obj["causes"].([]interface{}); ok {
+			for _, c := range causes {
+				if cause, ok := c.(map[string]interface{}); ok {
+					buf.AppendByte('\n')
+					buf.AppendString(prettyIndent + "  ")
+					e.paint(buf, colorDim, fmt.Sprintf("caused by %v: %v", cause["type"], cause["msg"]))
+				}
+			}
+		}
+		if stack, ok := obj["stack"].(string); ok {
+			e.appendBlock(buf, "stack", stack)
+		}
+		return
+	}
+
+	buf.AppendByte('\n')
+	buf.AppendString(prettyIndent)
+	e.paint(buf, color, key+":")
+	for _, line := range strings.Split(strings.TrimRight(fmt.Sprint(value), "\n"), "\n") {
+		buf.AppendByte('\n')
+		buf.AppendString(prettyIndent + "  ")
+		e.paint(buf, colorDim, line)
+	}
+}
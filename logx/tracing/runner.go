@@ -0,0 +1,171 @@
+package tracing
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueueStatus is the status of the queue of the hooks or the OTLP exporter
+// of a logger.
+type QueueStatus struct {
+	Name     string `json:"name"`
+	Queued   int    `json:"queued"`
+	Capacity int    `json:"capacity"`
+
+	// Dropped is the number of entries dropped because the queue was full or
+	// the logger was closed.
+	Dropped uint64 `json:"dropped"`
+}
+
+var (
+	_runnersMu sync.Mutex
+	_runners   = map[*asyncRunner]struct{}{}
+)
+
+// QueueStatuses returns the status of the queues of all the loggers which
+// are not closed, sorted by name.
+func QueueStatuses() []QueueStatus {
+	_runnersMu.Lock()
+	statuses := make([]QueueStatus, 0, len(_runners))
+	for r := range _runners {
+		statuses = append(statuses, QueueStatus{
+			Name:     r.name,
+			Queued:   len(r.queue),
+			Capacity: cap(r.queue),
+			Dropped:  atomic.LoadUint64(&r.dropped),
+		})
+	}
+	_runnersMu.Unlock()
+
+	sort.SliceStable(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// asyncRunner queues items without blocking the caller, and handles them in
+// batches in a single goroutine, in the order they were pushed. A batch is
+// handled when it is full, every interval if it is positive, on flush and on
+// Close.
+type asyncRunner struct {
+	name      string
+	handle    func([]interface{})
+	batchSize int
+	interval  time.Duration
+
+	queue   chan interface{}
+	flushes chan chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+	dropped uint64
+}
+
+func newAsyncRunner(name string, size int, batchSize int, interval time.Duration, handle func([]interface{})) *asyncRunner {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	r := &asyncRunner{
+		name:      name,
+		handle:    handle,
+		batchSize: batchSize,
+		interval:  interval,
+		queue:     make(chan interface{}, size),
+		flushes:   make(chan chan struct{}),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+
+	_runnersMu.Lock()
+	_runners[r] = struct{}{}
+	_runnersMu.Unlock()
+
+	go r.run()
+	return r
+}
+
+func (r *asyncRunner) run() {
+	var tick <-chan time.Time
+	if r.interval > 0 {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	batch := make([]interface{}, 0, r.batchSize)
+	handle := func() {
+		if len(batch) == 0 {
+			return
+		}
+		r.handle(batch)
+		batch = make([]interface{}, 0, r.batchSize)
+	}
+	add := func(item interface{}) {
+		batch = append(batch, item)
+		if len(batch) >= r.batchSize {
+			handle()
+		}
+	}
+	drain := func() {
+		for n := len(r.queue); n > 0; n-- {
+			add(<-r.queue)
+		}
+		handle()
+	}
+
+	for {
+		select {
+		case item := <-r.queue:
+			add(item)
+		case <-tick:
+			handle()
+		case done := <-r.flushes:
+			drain()
+			close(done)
+		case <-r.stop:
+			drain()
+			close(r.stopped)
+			return
+		}
+	}
+}
+
+// push enqueues an item without blocking, the item is dropped when the
+// queue is full or the runner is closed.
+func (r *asyncRunner) push(item interface{}) {
+	select {
+	case <-r.stop:
+		atomic.AddUint64(&r.dropped, 1)
+		return
+	default:
+	}
+	select {
+	case r.queue <- item:
+	default:
+		atomic.AddUint64(&r.dropped, 1)
+	}
+}
+
+// flush waits until all the items queued before it have been handled.
+func (r *asyncRunner) flush() {
+	done := make(chan struct{})
+	select {
+	case r.flushes <- done:
+		<-done
+	case <-r.stopped:
+	}
+}
+
+// Close handles the queued items and stops the goroutine, the items pushed
+// after it are dropped.
+func (r *asyncRunner) Close() error {
+	r.once.Do(func() {
+		close(r.stop)
+		<-r.stopped
+
+		_runnersMu.Lock()
+		delete(_runners, r)
+		_runnersMu.Unlock()
+	})
+	return nil
+}
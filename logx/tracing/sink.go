@@ -0,0 +1,370 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	defaultSinkDialTimeout  = 3 * time.Second
+	defaultSinkWriteTimeout = 3 * time.Second
+	defaultSinkQueueSize    = 1024
+
+	// minSinkRedial and maxSinkRedial bound the backoff of the redials of a
+	// tcp or udp sink.
+	minSinkRedial = 100 * time.Millisecond
+	maxSinkRedial = 30 * time.Second
+)
+
+// SinkConfig is an output of the logger with its own level range, encoding
+// and rotation.
+type SinkConfig struct {
+	// Type is the type of the sink. Valid values are "file", "stderr",
+	// "stdout", "tcp" and "udp"
+	Type string `json:"type" yaml:"type"`
+
+	// Level is the minimum level of the sink
+	// default is info
+	Level string `json:"level" yaml:"level"`
+
+	// MaxLevel is the maximum level of the sink
+	// default is no maximum level
+	MaxLevel string `json:"maxlevel" yaml:"maxlevel"`
+
+	// Encoding is the encoding of the sink, see Config.FileEncodeing
+	// default is console for stderr and stdout, and json for the others,
+	// gelf entries of a tcp sink end with a null byte instead of a newline
+	Encoding string `json:"encoding" yaml:"encoding"`
+
+	// Filename, MaxSize, MaxAge, MaxBackups, LocalTime and Compress are the
+	// file and the rotation of a file sink, see Config.Filename
+	Filename   string `json:"filename" yaml:"filename"`
+	MaxSize    int    `json:"maxsize" yaml:"maxsize"`
+	MaxAge     int    `json:"maxage" yaml:"maxage"`
+	MaxBackups int    `json:"maxbackups" yaml:"maxbackups"`
+	LocalTime  bool   `json:"localtime" yaml:"localtime"`
+	Compress   bool   `json:"compress" yaml:"compress"`
+
+	// Encrypt determines if a file sink is encrypted with AES-GCM, every
+	// entry is sealed in a frame, see NewDecryptReader
+	Encrypt bool `json:"encrypt" yaml:"encrypt"`
+
+	// EncryptionKey is the AES key in hex or base64
+	// default is the LOGX_ENCRYPTION_KEY environment variable
+	EncryptionKey string `json:"encryptionkey" yaml:"encryptionkey"`
+
+	// MinFreeSpace is the minimum free space in megabytes of the volume of a
+	// file sink, under it the sink is degraded. default is no minimum
+	MinFreeSpace int `json:"minfreespace" yaml:"minfreespace"`
+
+	// MaxTotalSize is the maximum size in megabytes of a file sink and its
+	// backups, over it the oldest backups are removed, and the sink is
+	// degraded if it is still over. default is no maximum
+	MaxTotalSize int `json:"maxtotalsize" yaml:"maxtotalsize"`
+
+	// DegradedLevel is the minimum level of a degraded file sink, which is
+	// also degraded after a failed write, see FileSinkStatuses. A file sink
+	// is only guarded if MinFreeSpace, MaxTotalSize or DegradedLevel is set
+	// default is error
+	DegradedLevel string `json:"degradedlevel" yaml:"degradedlevel"`
+
+	// DiskCheckInterval is the minimum time in milliseconds between two
+	// checks of the disk of a guarded file sink. default is 10000
+	DiskCheckInterval int `json:"diskcheckinterval" yaml:"diskcheckinterval"`
+
+	// Address is the host:port of a tcp or udp sink
+	Address string `json:"address" yaml:"address"`
+
+	// QueueSize is the number of entries buffered for a tcp or udp sink,
+	// entries are dropped when it is full or the sink is disconnected, see
+	// QueueStatuses. default is 1024
+	QueueSize int `json:"queuesize" yaml:"queuesize"`
+
+	// IncludeFields are the keys of the only fields written to the sink
+	// default is all the fields
+	IncludeFields []string `json:"includefields" yaml:"includefields"`
+
+	// ExcludeFields are the keys of the fields not written to the sink,
+	// e.g. LIP and LPID
+	ExcludeFields []string `json:"excludefields" yaml:"excludefields"`
+
+	// MaxFieldLength is the maximum length in bytes of string field values,
+	// longer values are truncated and marked with TruncatedMarker, a
+	// negative value disables it. default is Config.MaxFieldLength
+	MaxFieldLength int `json:"maxfieldlength" yaml:"maxfieldlength"`
+}
+
+// sinks returns the sinks of the config, EnableFile and EnableConsole are
+// shorthands for a file sink and a stderr sink which precede Sinks.
+func (c Config) sinks() []SinkConfig {
+	sinks := make([]SinkConfig, 0, len(c.Sinks)+2)
+	if c.EnableFile {
+		sinks = append(sinks, SinkConfig{
+			Type:       "file",
+			Level:      c.FileLevel,
+			Encoding:   shorthandEncoding(c.FileEncodeing),
+			Filename:   c.Filename,
+			MaxSize:    c.MaxSize,
+			MaxAge:     c.MaxAge,
+			MaxBackups: c.MaxBackups,
+			LocalTime:  c.LocalTime,
+			Compress:   c.Compress,
+
+			Encrypt:       c.EncryptFile,
+			EncryptionKey: c.EncryptionKey,
+
+			MinFreeSpace:  c.MinFreeSpace,
+			MaxTotalSize:  c.MaxTotalSize,
+			DegradedLevel: c.DegradedLevel,
+		})
+	}
+	if c.EnableConsole {
+		sinks = append(sinks, SinkConfig{
+			Type:     "stderr",
+			Level:    c.ConsoleLevel,
+			Encoding: shorthandEncoding(c.ConsoleEncodeing),
+		})
+	}
+	return append(sinks, c.Sinks...)
+}
+
+// shorthandEncoding returns the encoding of a shorthand sink, which is
+// console if empty.
+func shorthandEncoding(encoding string) string {
+	if encoding == "" {
+		return "console"
+	}
+	return encoding
+}
+
+// newSinkCore returns the core writing to the sink, and the closer of its
+// file or connection, nil for stderr and stdout.
+func newSinkCore(config Config, sink SinkConfig) (zapcore.Core, io.Closer, error) {
+	var w zapcore.WriteSyncer
+	var closer io.Closer
+	var guard *diskGuard
+	color := false
+	lineEnding := ""
+	encoding := sink.Encoding
+
+	switch strings.ToLower(sink.Type) {
+	case "file", "":
+		lj := &lumberjack.Logger{
+			Filename:   sink.Filename,
+			MaxSize:    sink.MaxSize,
+			MaxBackups: sink.MaxBackups,
+			MaxAge:     sink.MaxAge,
+			LocalTime:  sink.LocalTime,
+			Compress:   sink.Compress,
+		}
+		if sink.MinFreeSpace > 0 || sink.MaxTotalSize > 0 || sink.DegradedLevel != "" {
+			guard = newDiskGuard(sink)
+		}
+		fs := &fileSink{Writer: lj, file: lj, guard: guard}
+		if sink.Encrypt {
+			key, err := encryptionKey(sink.EncryptionKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			if fs.Writer, err = newEncryptWriter(lj, key); err != nil {
+				return nil, nil, err
+			}
+		}
+		w, closer = fs, fs
+		if encoding == "" {
+			encoding = "json"
+		}
+	case "stderr", "stdout":
+		f := os.Stderr
+		if strings.ToLower(sink.Type) == "stdout" {
+			f = os.Stdout
+		}
+		w = zapcore.Lock(f)
+		color = isTerminal(f)
+		if encoding == "" {
+			encoding = "console"
+		}
+	case "tcp", "udp":
+		if sink.Address == "" {
+			return nil, nil, errors.New("no address of " + sink.Type + " sink")
+		}
+		network := strings.ToLower(sink.Type)
+		nw := newNetWriter(network, sink.Address, sink.QueueSize)
+		w, closer = nw, nw
+		if encoding == "" {
+			encoding = "json"
+		}
+		// GELF over tcp is delimited by a null byte
+		if network == "tcp" && encoding == "gelf" {
+			lineEnding = "\x00"
+		}
+	default:
+		return nil, nil, errors.New("unknown sink type: " + sink.Type)
+	}
+
+	min := parseLevel(sink.Level)
+	max := zapcore.FatalLevel
+	if sink.MaxLevel != "" {
+		max = parseLevel(sink.MaxLevel)
+	}
+	enab := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= min && l <= max
+	})
+	maxLen := sink.MaxFieldLength
+	if maxLen == 0 {
+		maxLen = config.MaxFieldLength
+	}
+	core := newCore(config, enab, encoding, w, color, lineEnding)
+	core = newFilterCore(core, sink.IncludeFields, sink.ExcludeFields, maxLen)
+	if guard != nil {
+		core = &guardCore{Core: core, guard: guard}
+	}
+	return core, closer, nil
+}
+
+// fileSink writes to a rotated file, Sync flushes the file to the disk, so
+// an entry written by Fatal or Panic survives the crash of the host. The
+// results of the writes are reported to its guard if it has one.
+type fileSink struct {
+	io.Writer
+	file  *lumberjack.Logger
+	guard *diskGuard
+}
+
+func (s *fileSink) Write(p []byte) (int, error) {
+	n, err := s.Writer.Write(p)
+	if s.guard != nil {
+		s.guard.wrote(err)
+	}
+	return n, err
+}
+
+func (s *fileSink) Sync() error {
+	if s.file.Filename == "" {
+		return nil
+	}
+	// lumberjack hides its file, a descriptor of the same file flushes it
+	f, err := os.OpenFile(s.file.Filename, os.O_WRONLY, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+func (s *fileSink) Close() error {
+	if s.guard != nil {
+		s.guard.unregister()
+	}
+	return s.file.Close()
+}
+
+// netWriter writes every entry to a tcp connection or as a udp datagram in
+// the goroutine of its runner, so a down or stalled peer never blocks the
+// caller. The connection is dialed on the first entry and redialed after an
+// error with a backoff, the entries are dropped until it is connected.
+type netWriter struct {
+	network string
+	address string
+	runner  *asyncRunner
+
+	// conn, redial and wait are only used by the goroutine of the runner
+	conn   net.Conn
+	redial time.Time
+	wait   time.Duration
+}
+
+func newNetWriter(network string, address string, size int) *netWriter {
+	if size <= 0 {
+		size = defaultSinkQueueSize
+	}
+	w := &netWriter{network: network, address: address}
+	w.runner = newAsyncRunner(network+" "+address, size, 1, 0, w.send)
+	return w
+}
+
+// Write queues a copy of the entry, it never blocks.
+func (w *netWriter) Write(p []byte) (int, error) {
+	w.runner.push(append([]byte(nil), p...))
+	return len(p), nil
+}
+
+// send writes a batch of the runner.
+func (w *netWriter) send(batch []interface{}) {
+	for _, item := range batch {
+		if err := w.write(item.([]byte)); err != nil {
+			atomic.AddUint64(&w.runner.dropped, 1)
+		}
+	}
+}
+
+func (w *netWriter) write(p []byte) error {
+	if w.conn == nil {
+		if time.Now().Before(w.redial) {
+			return errSinkDisconnected
+		}
+		conn, err := net.DialTimeout(w.network, w.address, defaultSinkDialTimeout)
+		if err != nil {
+			w.disconnect(fmt.Errorf("dial: %w", err))
+			return err
+		}
+		w.conn, w.wait = conn, 0
+	}
+
+	w.conn.SetWriteDeadline(time.Now().Add(defaultSinkWriteTimeout))
+	if _, err := w.conn.Write(p); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		w.disconnect(err)
+		return err
+	}
+	return nil
+}
+
+var errSinkDisconnected = errors.New("sink disconnected")
+
+// disconnect reports the error and doubles the time until the next dial.
+func (w *netWriter) disconnect(err error) {
+	if w.wait == 0 {
+		fmt.Fprintf(os.Stderr, "logx: %s sink %s: %v, entries are dropped until it reconnects\n", w.network, w.address, err)
+	}
+	w.wait *= 2
+	if w.wait < minSinkRedial {
+		w.wait = minSinkRedial
+	}
+	if w.wait > maxSinkRedial {
+		w.wait = maxSinkRedial
+	}
+	w.redial = time.Now().Add(w.wait)
+}
+
+// Sync waits until the queued entries are written.
+func (w *netWriter) Sync() error {
+	w.runner.flush()
+	return nil
+}
+
+// Close writes the queued entries, stops the runner and closes the
+// connection.
+func (w *netWriter) Close() error {
+	w.runner.Close()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
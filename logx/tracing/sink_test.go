@@ -0,0 +1,209 @@
+package tracing_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+)
+
+func TestSinks(t *testing.T) {
+	dir := t.TempDir()
+	shipper := filepath.Join(dir, "shipper.log")
+	debug := filepath.Join(dir, "debug.log")
+
+	config := tracing.Config{AppName: "logx_test"}
+	logger := tracing.NewLogger(config, tracing.WithSinks(
+		tracing.SinkConfig{Type: "file", Filename: shipper, Level: "info"},
+		tracing.SinkConfig{Type: "file", Filename: debug, Level: "debug", MaxLevel: "info", Encoding: "logfmt"},
+	))
+
+	ctx := tracing.NewTraceCtx("t1")
+	logger.Debug(ctx, "debug message")
+	logger.Info(ctx, "info message")
+	logger.Error(ctx, "error message")
+
+	entries := readEntries(t, shipper)
+	if len(entries) != 2 || entries[0]["M"] != "info message" || entries[1]["M"] != "error message" {
+		t.Errorf("unexpected json sink entries %v", entries)
+	}
+
+	b, err := os.ReadFile(debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `M="debug message"`) || !strings.Contains(lines[1], `M="info message"`) {
+		t.Errorf("unexpected logfmt sink lines %q", lines)
+	}
+}
+
+func TestSinksShorthand(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	errFile := filepath.Join(dir, "error.log")
+
+	config := NewTestConfig(filename)
+	config.Sinks = []tracing.SinkConfig{{Filename: errFile, Level: "error"}}
+	logger := tracing.NewLogger(config)
+
+	ctx := tracing.NewTraceCtx("t1")
+	logger.Info(ctx, "info message")
+	logger.Error(ctx, "error message")
+
+	if entries := readEntries(t, filename); len(entries) != 2 {
+		t.Errorf("expected 2 entries in the flat file, got %d", len(entries))
+	}
+	if entries := readEntries(t, errFile); len(entries) != 1 || entries[0]["M"] != "error message" {
+		t.Errorf("unexpected error sink entries %v", entries)
+	}
+}
+
+func TestSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan map[string]interface{}, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			entry := map[string]interface{}{}
+			json.Unmarshal(scanner.Bytes(), &entry)
+			received <- entry
+		}
+	}()
+
+	logger := tracing.NewLogger(tracing.Config{AppName: "logx_test"},
+		tracing.WithSinks(tracing.SinkConfig{Type: "tcp", Address: ln.Addr().String()}))
+	logger.Warn(tracing.NewTraceCtx("t1"), "sent over tcp")
+
+	select {
+	case e := <-received:
+		if e["M"] != "sent over tcp" || e["TRACE_ID"] != "t1" {
+			t.Errorf("unexpected entry %v", e)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no entry received")
+	}
+}
+
+func TestSinkTCPDown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	// nothing listens on the address
+	ln.Close()
+
+	logger := tracing.NewLogger(tracing.Config{AppName: "logx_test"},
+		tracing.WithSinks(tracing.SinkConfig{Type: "tcp", Address: address}))
+	defer logger.Close()
+
+	start := time.Now()
+	ctx := tracing.NewTraceCtx("t1")
+	for i := 0; i < 5; i++ {
+		logger.Warn(ctx, "collector down")
+	}
+	logger.Sync()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("logging blocked for %s", elapsed)
+	}
+	if s := queueStatus("tcp " + address); s == nil || s.Dropped != 5 {
+		t.Errorf("unexpected queue status %+v", s)
+	}
+}
+
+func TestSinkFieldFilters(t *testing.T) {
+	dir := t.TempDir()
+	console := filepath.Join(dir, "console.log")
+	errFile := filepath.Join(dir, "error.log")
+	archive := filepath.Join(dir, "archive.log")
+
+	config := tracing.Config{AppName: "logx_test", EnablePID: true, EnableSourceIP: true, MaxFieldLength: 12}
+	logger := tracing.NewLogger(config, tracing.WithSinks(
+		tracing.SinkConfig{Filename: console, ExcludeFields: []string{"LIP", "LPID"}},
+		tracing.SinkConfig{Filename: errFile, ExcludeFields: []string{"httpRequest"}, MaxFieldLength: -1},
+		tracing.SinkConfig{Filename: archive, IncludeFields: []string{"TRACE_ID", "user"}},
+	))
+
+	logger.WithField(httpRequest).With("user", "alice-from-accounting").Info(tracing.NewTraceCtx("t1"), "hello")
+
+	e := readEntries(t, console)[0]
+	if _, ok := e["LIP"]; ok {
+		t.Errorf("LIP is not excluded: %v", e)
+	}
+	if _, ok := e["LPID"]; ok {
+		t.Errorf("LPID is not excluded: %v", e)
+	}
+	if e["user"] != "alice-from-a"+tracing.TruncatedMarker || e["LAPP"] != "logx_test" {
+		t.Errorf("unexpected fields %v", e)
+	}
+
+	e = readEntries(t, errFile)[0]
+	if _, ok := e["httpRequest"]; ok {
+		t.Errorf("httpRequest is not excluded: %v", e)
+	}
+	if e["user"] != "alice-from-accounting" || e["LIP"] == nil {
+		t.Errorf("unexpected fields %v", e)
+	}
+
+	e = readEntries(t, archive)[0]
+	if len(e) != 5 || e["TRACE_ID"] != "t1" || e["user"] == nil || e["M"] != "hello" {
+		t.Errorf("only TRACE_ID and user are expected: %v", e)
+	}
+}
+
+func TestSinkTCPGELF(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if frame, err := r.ReadBytes(0); err == nil {
+			received <- frame
+		}
+	}()
+
+	logger := tracing.NewLogger(tracing.Config{AppName: "logx_test"},
+		tracing.WithSinks(tracing.SinkConfig{Type: "tcp", Address: ln.Addr().String(), Encoding: "gelf"}))
+	logger.Warn(tracing.NewTraceCtx("t1"), "gelf over tcp")
+
+	select {
+	case frame := <-received:
+		// the frame is delimited by a null byte, without a newline
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal(frame[:len(frame)-1], &entry); err != nil || bytes.HasSuffix(frame, []byte("\n\x00")) {
+			t.Fatalf("unexpected frame %q: %v", frame, err)
+		}
+		if entry["short_message"] != "gelf over tcp" || entry["_trace_id"] != "t1" {
+			t.Errorf("unexpected entry %v", entry)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no entry received")
+	}
+}
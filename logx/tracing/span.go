@@ -0,0 +1,266 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Span is a lightweight trace.Span which needs no OpenTelemetry SDK. Its span
+// context is stored on the context, so the log contains TRACE_ID, SPAN_ID
+// and PARENT_SPAN_ID, and a span-summary entry with the duration and status
+// is logged when it ends.
+type Span struct {
+	noop.Span
+
+	logger *VLogger
+	ctx    context.Context
+	sc     trace.SpanContext
+	parent trace.SpanContext
+	start  time.Time
+
+	mu     sync.Mutex
+	name   string
+	ended  bool
+	status codes.Code
+	desc   string
+	err    error
+	attrs  []attribute.KeyValue
+	events spanEvents
+}
+
+// spanEvent is an event added to a Span, which is logged when it ends.
+type spanEvent struct {
+	name   string
+	offset time.Duration
+	attrs  []attribute.KeyValue
+}
+
+func (e spanEvent) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", e.name)
+	enc.AddDuration("offset", e.offset)
+	for _, kv := range e.attrs {
+		if err := enc.AddReflected(string(kv.Key), kv.Value.AsInterface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type spanEvents []spanEvent
+
+func (es spanEvents) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, e := range es {
+		if err := enc.AppendObject(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartSpan starts a span on the global logger, see VLogger.StartSpan.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return _logger.StartSpan(ctx, name)
+}
+
+// StartSpan starts a span which is a child of the span in ctx. Without a span,
+// the trace ID of ctx is reused if it is a valid W3C trace ID, otherwise a new
+// trace ID is generated, and the trace ID of ctx is logged as REQUEST_ID so
+// the entries of the span are still correlated with the other entries of
+// the request.
+func (l *VLogger) StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	parent := trace.SpanContextFromContext(ctx)
+
+	var traceID trace.TraceID
+	if parent.HasTraceID() {
+		traceID = parent.TraceID()
+	} else if id, err := trace.TraceIDFromHex(GetTraceID(ctx)); err == nil {
+		traceID = id
+	} else {
+		traceID = newTraceID()
+	}
+
+	s := &Span{
+		logger: l,
+		sc: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     newSpanID(),
+			TraceFlags: trace.FlagsSampled,
+		}),
+		parent: parent,
+		start:  time.Now(),
+		name:   name,
+	}
+	s.ctx = trace.ContextWithSpan(ctx, s)
+	return s.ctx, s
+}
+
+// SpanContext returns the span context of the span.
+func (s *Span) SpanContext() trace.SpanContext {
+	return s.sc
+}
+
+// Parent returns the span context of the parent span, it is invalid for a
+// root span.
+func (s *Span) Parent() trace.SpanContext {
+	return s.parent
+}
+
+// IsRecording returns true until the span ends.
+func (s *Span) IsRecording() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.ended
+}
+
+// SetStatus sets the status logged when the span ends.
+func (s *Span) SetStatus(code codes.Code, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.desc = code, description
+}
+
+// SetName sets the span name.
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+
+// SetAttributes sets attributes logged when the span ends.
+func (s *Span) SetAttributes(kv ...attribute.KeyValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, kv...)
+}
+
+// AddEvent adds an event logged when the span ends, with its offset from the
+// start of the span and its attributes.
+func (s *Span) AddEvent(name string, options ...trace.EventOption) {
+	config := trace.NewEventConfig(options...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.events = append(s.events, spanEvent{name, config.Timestamp().Sub(s.start), config.Attributes()})
+}
+
+// RecordError records an error logged when the span ends, the span status is
+// not changed.
+func (s *Span) RecordError(err error, options ...trace.EventOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// End ends the span and logs the span-summary entry, it is logged at level
+// Error if the span status is Error and at level Info otherwise.
+func (s *Span) End(options ...trace.SpanEndOption) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	duration := time.Since(s.start)
+	name, status, desc, err := s.name, s.status, s.desc, s.err
+	attrs, events := s.attrs, s.events
+	s.mu.Unlock()
+
+	level := zapcore.InfoLevel
+	if status == codes.Error {
+		level = zapcore.ErrorLevel
+	}
+	// End is called by the user, not by a function of the package
+	log := s.logger.log.WithOptions(zap.AddCallerSkip(-s.logger.config.GlobalCallerSkip))
+	ce := log.Check(level, "span end: "+name)
+	if ce == nil {
+		return
+	}
+
+	fields := s.logger.getFields(s.ctx)
+	fields = append(fields,
+		zap.String("SPAN_NAME", name),
+		zap.Duration("DURATION", duration),
+		zap.String("STATUS", strings.ToUpper(status.String())),
+	)
+	if desc != "" {
+		fields = append(fields, zap.String("STATUS_DESC", desc))
+	}
+	for _, kv := range attrs {
+		fields = append(fields, zap.Any(string(kv.Key), kv.Value.AsInterface()))
+	}
+	if len(events) > 0 {
+		fields = append(fields, zap.Array("EVENTS", events))
+	}
+	fields = append(fields, ErrorField(err))
+	ce.Write(fields...)
+}
+
+func newTraceID() (id trace.TraceID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return
+}
+
+func newSpanID() (id trace.SpanID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return
+}
+
+// NewTraceID returns a random W3C trace ID in hex.
+func NewTraceID() string {
+	return newTraceID().String()
+}
+
+// FormatTraceparent returns the W3C traceparent header value of sc.
+func FormatTraceparent(sc trace.SpanContext) string {
+	return "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-" + sc.TraceFlags().String()
+}
+
+// ParseTraceparent parses a W3C traceparent header value, the returned span
+// context is marked as remote.
+func ParseTraceparent(s string) (trace.SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+		return trace.SpanContext{}, errors.New("invalid traceparent: " + s)
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return trace.SpanContext{}, errors.New("invalid traceparent: " + s)
+	}
+
+	traceID, err := trace.TraceIDFromHex(parts[1])
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+	spanID, err := trace.SpanIDFromHex(parts[2])
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.TraceFlags(flags[0]),
+		Remote:     true,
+	}), nil
+}
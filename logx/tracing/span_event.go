@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const spanFieldKey = "_span"
+
+// spanField carries the span of the context to the span event core, it is
+// ignored by the encoders.
+func spanField(span trace.Span) zap.Field {
+	return zap.Field{Key: spanFieldKey, Type: zapcore.SkipType, Interface: span}
+}
+
+// spanEventCore adds entries as events to the span carried by spanField, and
+// sets the span status to Error for entries at level Error or above.
+type spanEventCore struct {
+	zapcore.LevelEnabler
+	fields []zapcore.Field
+}
+
+func newSpanEventCore(config Config) zapcore.Core {
+	level := config.SpanEventLevel
+	if level == "" {
+		level = "warn"
+	}
+	return &spanEventCore{LevelEnabler: parseLevel(level)}
+}
+
+func (c *spanEventCore) With(fields []zapcore.Field) zapcore.Core {
+	return &spanEventCore{
+		LevelEnabler: c.LevelEnabler,
+		fields:       append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *spanEventCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *spanEventCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var span trace.Span
+	for _, f := range fields {
+		if f.Key == spanFieldKey && f.Type == zapcore.SkipType {
+			span, _ = f.Interface.(trace.Span)
+		}
+	}
+	if span == nil || !span.IsRecording() {
+		return nil
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	// the host fields are resource attributes of the span, and the trace
+	// context is the span itself
+	for key := range otlpResourceKeys {
+		delete(enc.Fields, key)
+	}
+	for _, key := range traceKeys {
+		delete(enc.Fields, key)
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(enc.Fields)+2)
+	attrs = append(attrs, attribute.String("log.severity", ent.Level.CapitalString()))
+	if ent.Caller.Defined {
+		attrs = append(attrs, attribute.String("code.filepath", ent.Caller.TrimmedPath()))
+	}
+	for k, v := range enc.Fields {
+		attrs = append(attrs, spanAttribute(k, v))
+	}
+
+	span.AddEvent(ent.Message, trace.WithTimestamp(ent.Time), trace.WithAttributes(attrs...))
+	if ent.Level >= zapcore.ErrorLevel {
+		span.SetStatus(codes.Error, ent.Message)
+	}
+	return nil
+}
+
+func (c *spanEventCore) Sync() error {
+	return nil
+}
+
+// spanAttribute converts a value produced by zapcore.MapObjectEncoder.
+func spanAttribute(k string, v interface{}) attribute.KeyValue {
+	switch v := v.(type) {
+	case string:
+		return attribute.String(k, v)
+	case bool:
+		return attribute.Bool(k, v)
+	case int:
+		return attribute.Int(k, v)
+	case int64:
+		return attribute.Int64(k, v)
+	case int32:
+		return attribute.Int64(k, int64(v))
+	case uint32:
+		return attribute.Int64(k, int64(v))
+	case float64:
+		return attribute.Float64(k, v)
+	case float32:
+		return attribute.Float64(k, float64(v))
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return attribute.String(k, fmt.Sprint(v))
+		}
+		return attribute.String(k, string(b))
+	default:
+		return attribute.String(k, fmt.Sprint(v))
+	}
+}
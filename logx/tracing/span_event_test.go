@@ -0,0 +1,70 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type spanEvent struct {
+	name  string
+	attrs map[attribute.Key]attribute.Value
+}
+
+// recordingSpan records the events and status set on it.
+type recordingSpan struct {
+	noop.Span
+	events []spanEvent
+	status codes.Code
+	desc   string
+}
+
+func (s *recordingSpan) IsRecording() bool { return true }
+
+func (s *recordingSpan) AddEvent(name string, opts ...trace.EventOption) {
+	cfg := trace.NewEventConfig(opts...)
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range cfg.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	s.events = append(s.events, spanEvent{name, attrs})
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, desc string) {
+	s.status, s.desc = code, desc
+}
+
+func TestSpanEvents(t *testing.T) {
+	config := tracing.NewStdConfig()
+	config.EnableConsole = false
+	config.EnableSpanEvents = true
+	config.EnableHostname = true
+	logger := tracing.NewLogger(config)
+
+	span := &recordingSpan{}
+	ctx := trace.ContextWithSpan(context.Background(), span)
+	logger.Info(ctx, "info is below the default level")
+	logger.With("order_id", "o-1").With("PARENT_SPAN_ID", "00f067aa0ba902b7").Warn(ctx, "order is slow")
+	logger.Error(ctx, "order failed")
+
+	if len(span.events) != 2 {
+		t.Fatalf("got %d span events, want 2", len(span.events))
+	}
+	e := span.events[0]
+	if e.name != "order is slow" || e.attrs["order_id"].AsString() != "o-1" || e.attrs["log.severity"].AsString() != "WARN" {
+		t.Errorf("unexpected span event %v", e)
+	}
+	for _, key := range []attribute.Key{"LAPP", "LHOST", "PARENT_SPAN_ID"} {
+		if _, ok := e.attrs[key]; ok {
+			t.Errorf("span event must not contain %s", key)
+		}
+	}
+	if span.status != codes.Error || span.desc != "order failed" {
+		t.Errorf("span status is %v %q, want Error", span.status, span.desc)
+	}
+}
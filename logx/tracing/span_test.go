@@ -0,0 +1,128 @@
+package tracing_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestStartSpan(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "span.log")
+	logger := tracing.NewLogger(NewTestConfig(filename))
+
+	traceID := tracing.NewTraceID()
+	ctx, root := logger.StartSpan(tracing.NewTraceCtx(traceID), "handle")
+	childCtx, child := logger.StartSpan(ctx, "query")
+	logger.Info(childCtx, "querying")
+	child.SetStatus(codes.Error, "timeout")
+	child.End()
+	root.End()
+	root.End()
+
+	entries := readEntries(t, filename)
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+
+	rootID := root.SpanContext().SpanID().String()
+	childID := child.SpanContext().SpanID().String()
+	for _, e := range entries {
+		if e["TRACE_ID"] != traceID {
+			t.Errorf("entry has TRACE_ID %v, want %s", e["TRACE_ID"], traceID)
+		}
+	}
+	if e := entries[0]; e["SPAN_ID"] != childID || e["PARENT_SPAN_ID"] != rootID {
+		t.Errorf("unexpected entry %v", e)
+	}
+	if caller, _ := entries[1]["LFILE"].(string); !strings.Contains(caller, "span_test.go") {
+		t.Errorf("unexpected caller %q", caller)
+	}
+	if e := entries[1]; e["L"] != "ERROR" || e["SPAN_NAME"] != "query" || e["STATUS"] != "ERROR" || e["DURATION"] == nil {
+		t.Errorf("unexpected child summary %v", e)
+	}
+	if e := entries[2]; e["L"] != "INFO" || e["SPAN_ID"] != rootID || e["PARENT_SPAN_ID"] != nil || e["STATUS"] != "UNSET" {
+		t.Errorf("unexpected root summary %v", e)
+	}
+}
+
+func TestStartSpanRequestID(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "span.log")
+	logger := tracing.NewLogger(NewTestConfig(filename))
+
+	// not a W3C trace ID
+	ctx := tracing.NewTraceCtx("req-123")
+	logger.Info(ctx, "before the span")
+	ctx, span := logger.StartSpan(ctx, "handle")
+	logger.Info(ctx, "in the span")
+	span.End()
+
+	entries := readEntries(t, filename)
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	if e := entries[0]; e["TRACE_ID"] != "req-123" || e["REQUEST_ID"] != nil {
+		t.Errorf("unexpected entry %v", e)
+	}
+	traceID := span.SpanContext().TraceID().String()
+	for _, e := range entries[1:] {
+		if e["TRACE_ID"] != traceID || e["REQUEST_ID"] != "req-123" {
+			t.Errorf("unexpected entry %v", e)
+		}
+	}
+}
+
+func TestSpanEnd(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "span.log")
+	t.Cleanup(tracing.SetConfig(NewTestConfig(filename)))
+
+	ctx := tracing.WithFields(tracing.NewTraceCtx(tracing.NewTraceID()), "tenant", "acme")
+	ctx, span := tracing.StartSpan(ctx, "handle")
+	span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", 2)))
+	span.End()
+	span.AddEvent("after end")
+	tracing.GetLogger().Sync()
+
+	entries := readEntries(t, filename)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if caller, _ := e["LFILE"].(string); !strings.Contains(caller, "span_test.go") {
+		t.Errorf("unexpected caller %q", caller)
+	}
+	if e["tenant"] != "acme" || e["SPAN_ID"] != span.SpanContext().SpanID().String() {
+		t.Errorf("unexpected entry %v", e)
+	}
+	events, _ := e["EVENTS"].([]interface{})
+	if len(events) != 1 {
+		t.Fatalf("unexpected events %v", e["EVENTS"])
+	}
+	if ev, _ := events[0].(map[string]interface{}); ev["name"] != "retry" || ev["attempt"] != float64(2) || ev["offset"] == nil {
+		t.Errorf("unexpected event %v", ev)
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := tracing.ParseTraceparent(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.IsRemote() || !sc.IsSampled() {
+		t.Errorf("unexpected span context %v", sc)
+	}
+	if got := tracing.FormatTraceparent(sc); got != header {
+		t.Errorf("got %s, want %s", got, header)
+	}
+
+	for _, bad := range []string{"", "00-abc-def-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"} {
+		if _, err := tracing.ParseTraceparent(bad); err == nil {
+			t.Errorf("ParseTraceparent(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Status is the status of the file sinks and the queues of all the loggers.
+type Status struct {
+	FileSinks []SinkStatus  `json:"filesinks"`
+	Queues    []QueueStatus `json:"queues"`
+}
+
+// Degraded returns true if a file sink is degraded.
+func (s Status) Degraded() bool {
+	for _, sink := range s.FileSinks {
+		if sink.Degraded {
+			return true
+		}
+	}
+	return false
+}
+
+// GetStatus returns the status of the file sinks and the queues of all the
+// loggers, see also the package logx/expvarx.
+func GetStatus() Status {
+	return Status{
+		FileSinks: FileSinkStatuses(),
+		Queues:    QueueStatuses(),
+	}
+}
+
+// StatusHandler serves GetStatus as JSON, with the status code 503 if a file
+// sink is degraded, e.g. for an internal health endpoint.
+func StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := GetStatus()
+		code := http.StatusOK
+		if status.Degraded() {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(status)
+	})
+}
@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// stdLogCallerSkip skips the flush closure, loggerWriter.Write,
+// log.(*Logger).output and log.Printf, so the caller is the caller of the std
+// logger.
+const stdLogCallerSkip = 4
+
+// NewStdLogger returns a *log.Logger which writes to l at the specified level,
+// e.g. for net/http.Server.ErrorLog. Lines with a level prefix such as
+// "[ERROR]", "WARN:" or "level=error" are logged at that level instead. The
+// standard library's package-global logger is not changed.
+func NewStdLogger(l *VLogger, level string, fields ...zap.Field) *log.Logger {
+	if len(fields) > 0 {
+		l = l.WithField(fields...)
+	}
+	return log.New(newLoggerWriter(l, level), "", 0)
+}
+
+// loggerWriter logs every line written to it, lines starting with spaces or
+// tabs continue the previous line, e.g. stacktraces.
+type loggerWriter struct {
+	ctx   context.Context
+	log   *VLogger
+	level zapcore.Level
+}
+
+func newLoggerWriter(l *VLogger, level string) *loggerWriter {
+	return &loggerWriter{
+		ctx:   NewTraceCtx("stdlog"),
+		log:   l.AddCallerSkip(stdLogCallerSkip - l.config.GlobalCallerSkip),
+		level: parseLevel(level),
+	}
+}
+
+func (w *loggerWriter) Write(p []byte) (int, error) {
+	var record []byte
+	flush := func() {
+		if len(record) > 0 {
+			w.writeRecord(string(record))
+			record = record[:0]
+		}
+	}
+
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		line = bytes.TrimRight(line, " \t\r")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if len(record) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			record = append(record, '\n')
+			record = append(record, line...)
+			continue
+		}
+		flush()
+		record = append(record, bytes.TrimSpace(line)...)
+	}
+	flush()
+	return len(p), nil
+}
+
+func (w *loggerWriter) writeRecord(record string) {
+	level, msg := parseStdLogLevel(record, w.level)
+	if ce := w.log.log.Check(level, msg); ce != nil {
+		ce.Write(w.log.getFields(w.ctx)...)
+	}
+}
+
+// stdLogLevels are the level names recognized in std log lines. Fatal and
+// panic are logged at level Error, a library must not exit the process
+// through its log output.
+var stdLogLevels = map[string]zapcore.Level{
+	"DEBUG":    zapcore.DebugLevel,
+	"TRACE":    zapcore.DebugLevel,
+	"INFO":     zapcore.InfoLevel,
+	"NOTICE":   zapcore.InfoLevel,
+	"WARN":     zapcore.WarnLevel,
+	"WARNING":  zapcore.WarnLevel,
+	"ERROR":    zapcore.ErrorLevel,
+	"ERR":      zapcore.ErrorLevel,
+	"CRITICAL": zapcore.ErrorLevel,
+	"FATAL":    zapcore.ErrorLevel,
+	"PANIC":    zapcore.ErrorLevel,
+}
+
+// parseStdLogLevel detects the level of a std log line. "[ERROR] msg" and
+// "ERROR: msg" prefixes are removed from the message, "level=error" is kept.
+func parseStdLogLevel(line string, def zapcore.Level) (zapcore.Level, string) {
+	if strings.HasPrefix(line, "[") {
+		if end := strings.IndexByte(line, ']'); end > 0 {
+			if l, ok := stdLogLevels[strings.ToUpper(line[1:end])]; ok {
+				return l, strings.TrimSpace(line[end+1:])
+			}
+		}
+	}
+
+	if colon := strings.IndexByte(line, ':'); colon > 0 && colon <= len("CRITICAL") {
+		if l, ok := stdLogLevels[strings.ToUpper(line[:colon])]; ok {
+			return l, strings.TrimSpace(line[colon+1:])
+		}
+	}
+
+	if i := strings.Index(strings.ToLower(line), "level="); i >= 0 && (i == 0 || line[i-1] == ' ') {
+		value := line[i+len("level="):]
+		if end := strings.IndexAny(value, " \n"); end >= 0 {
+			value = value[:end]
+		}
+		if l, ok := stdLogLevels[strings.ToUpper(strings.Trim(value, `"`))]; ok {
+			return l, line
+		}
+	}
+	return def, line
+}
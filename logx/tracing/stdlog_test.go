@@ -0,0 +1,63 @@
+package tracing_test
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kakabei/kfgolib/logx/tracing"
+	"go.uber.org/zap"
+)
+
+func TestNewStdLogger(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "stdlog.log")
+	logger := tracing.NewLogger(NewTestConfig(filename))
+
+	std := tracing.NewStdLogger(logger, "warn", zap.String("component", "http"))
+	std.Print("plain line")
+	std.Print("[ERROR] broken pipe")
+	std.Print("info: server started")
+	std.Print("time=now level=debug msg=hello")
+	std.Print("panic: boom\n\tgoroutine 1\n\tmain.go:10\nnext line")
+
+	entries := readEntries(t, filename)
+	want := []struct {
+		level string
+		msg   string
+	}{
+		{"WARN", "plain line"},
+		{"ERROR", "broken pipe"},
+		{"INFO", "server started"},
+		{"DEBUG", "time=now level=debug msg=hello"},
+		{"ERROR", "boom\n\tgoroutine 1\n\tmain.go:10"},
+		{"WARN", "next line"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e["L"] != w.level || e["M"] != w.msg || e["component"] != "http" || e["TRACE_ID"] != "stdlog" {
+			t.Errorf("entry %d is %v, want %s %q", i, e, w.level, w.msg)
+		}
+		if !strings.HasPrefix(e["LFILE"].(string), "tracing/stdlog_test.go") {
+			t.Errorf("caller is %v, want stdlog_test.go", e["LFILE"])
+		}
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "redirect.log")
+	restore, _ := tracing.RedirectStdLog(tracing.NewLogger(NewTestConfig(filename)))
+	log.Printf("WARNING: disk %d%% full", 90)
+	restore()
+
+	entries := readEntries(t, filename)
+	if len(entries) != 1 || entries[0]["L"] != "WARN" || entries[0]["M"] != "disk 90% full" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+	if !strings.HasPrefix(entries[0]["LFILE"].(string), "tracing/stdlog_test.go") {
+		t.Errorf("caller is %v, want stdlog_test.go", entries[0]["LFILE"])
+	}
+}
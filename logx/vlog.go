@@ -2,8 +2,9 @@ package logx
 
 import (
 	"context"
+	"log"
 
-	"github.com/kakabei/kgo-public/logx/tracing"
+	"github.com/kakabei/kfgolib/logx/tracing"
 
 	"go.uber.org/zap"
 )
@@ -166,6 +167,56 @@ func PanicfContext(ctx context.Context, format string, args ...interface{}) {
 	GetLogger().Panicf(ctx, format, args...)
 }
 
+// Err logs a message at level Error on the VLogger, with the error's type,
+// message, wrapped causes and stack trace.
+func Err(err error, msg string, fields ...zap.Field) {
+	GetLogger().Err(oldCtx, err, msg, fields...)
+}
+
+func ErrContext(ctx context.Context, err error, msg string, fields ...zap.Field) {
+	GetLogger().Err(ctx, err, msg, fields...)
+}
+
+// ErrAt logs a message at the specified level on the VLogger, with the error's
+// type, message, wrapped causes and stack trace.
+func ErrAt(level string, err error, msg string, fields ...zap.Field) {
+	GetLogger().ErrAt(oldCtx, level, err, msg, fields...)
+}
+
+func ErrAtContext(ctx context.Context, level string, err error, msg string, fields ...zap.Field) {
+	GetLogger().ErrAt(ctx, level, err, msg, fields...)
+}
+
+// Audit appends an entry to the audit file, see tracing.VLogger.Audit. The
+// actor did the action on the resource, and the outcome is e.g. "success"
+// or "denied".
+func Audit(ctx context.Context, actor, action, resource, outcome string, fields ...zap.Field) error {
+	return GetLogger().Audit(ctx, actor, action, resource, outcome, fields...)
+}
+
+// Close flushes the entries and closes the sinks of the global logger.
+func Close() error {
+	return tracing.Close()
+}
+
+// HandlePanic recovers a panic of the goroutine, logs it with the global
+// logger and panics again, see VLogger.HandlePanic.
+func HandlePanic(ctx context.Context) {
+	if v := recover(); v != nil {
+		GetLogger().Repanic(ctx, v)
+	}
+}
+
+// HandleSignals closes the global logger and the loggers on SIGTERM and
+// SIGINT before the process exits, see tracing.HandleSignals.
+func HandleSignals(loggers ...*VLogger) (stop func()) {
+	tl := make([]*tracing.VLogger, len(loggers))
+	for i, l := range loggers {
+		tl[i] = l.log
+	}
+	return tracing.HandleSignals(tl...)
+}
+
 // With return a logger with an extra field.
 func With(key string, value interface{}) *VLogger {
 	return &VLogger{log: GetLogger().With(key, value)}
@@ -187,9 +238,10 @@ func AddCallerSkip(skip int) *VLogger {
 }
 
 // RedirectStdLog redirects output from the standard library's package-global
-// logger to the supplied logger at InfoLevel. Since zap already handles caller
-// annotations, timestamps, etc., it automatically disables the standard
-// library's annotations and prefixing.
+// logger to the supplied logger at InfoLevel, unless a line has a level
+// prefix such as "[ERROR]", "WARN:" or "level=error". Since zap already
+// handles caller annotations, timestamps, etc., it automatically disables the
+// standard library's annotations and prefixing.
 //
 // It returns a function to restore the original prefix and flags and reset the
 // standard library's output to os.Stderr.
@@ -198,7 +250,8 @@ func RedirectStdLog(l *VLogger) (func(), error) {
 }
 
 // RedirectStdLogAt redirects output from the standard library's package-global
-// logger to the supplied logger at the specified level. Since zap already
+// logger to the supplied logger at the specified level, unless a line has a
+// level prefix such as "[ERROR]", "WARN:" or "level=error". Since zap already
 // handles caller annotations, timestamps, etc., it automatically disables the
 // standard library's annotations and prefixing.
 //
@@ -211,3 +264,11 @@ func RedirectStdLogAt(l *VLogger, level string) (func(), error) {
 func ReplaceStdLog() (func(), error) {
 	return tracing.ReplaceStdLog()
 }
+
+// NewStdLogger returns a *log.Logger which writes to the global logger at the
+// specified level, e.g. for net/http.Server.ErrorLog. Lines with a level
+// prefix such as "[ERROR]" are logged at that level instead. The standard
+// library's package-global logger is not changed.
+func NewStdLogger(level string, fields ...zap.Field) *log.Logger {
+	return tracing.NewStdLogger(GetLogger(), level, fields...)
+}
@@ -3,6 +3,7 @@ package logx
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/kakabei/kfgolib/logx/tracing"
 )
@@ -11,3 +12,19 @@ func TestVLog(t *testing.T) {
 	Info("test logx.Debug")
 	tracing.Info(context.Background(), "test tracing.Debug")
 }
+
+func TestPrintfLogger(t *testing.T) {
+	// created before the global logger is replaced
+	p := NewPrintfLogger("warn")
+	entries := captureEntries(t)
+	p.Printf("retry %d", 2)
+
+	select {
+	case e := <-entries:
+		if e["M"] != "retry 2" {
+			t.Errorf("unexpected entry %v", e)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("the printf logger did not log on the replaced global logger")
+	}
+}